@@ -0,0 +1,308 @@
+package ctloglists
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+// AcceptedRoot describes a root certificate included in at least one log's Accepted Roots.
+type AcceptedRoot struct {
+	DER          []byte
+	Fingerprint  [sha256.Size]byte // SHA-256 hash of DER.
+	SPKIHash     [sha256.Size]byte // SHA-256 hash of the DER-encoded SubjectPublicKeyInfo.
+	Subject      string
+	Issuer       string
+	NotBefore    time.Time
+	NotAfter     time.Time
+	KeyAlgorithm string // e.g. "RSA 4096" or "ECDSA P-384".
+	SelfSigned   bool
+}
+
+// QuarantinedRoot describes a certificate in a log's Accepted Roots that could not be decoded or parsed, and which has therefore been excluded.
+type QuarantinedRoot struct {
+	LogID [sha256.Size]byte `json:"-"`
+	// Source is "get-roots" if the certificate was quarantined by the acceptedroots collector, in which case Index is its position in the log's get-roots response and Raw is the base64 string as returned; otherwise Source is the roots_<hash>.pem file from which it could not be loaded, Index is its position in that file and Raw is the PEM block's contents.
+	Source string `json:"source"`
+	Index  int    `json:"index"`
+	Raw    []byte `json:"raw"`
+	Error  string `json:"error"`
+}
+
+// acceptedRootsIndex holds per-list AcceptedRoot slices, and reverse indexes from root certificates to the Accepted Roots lists that include them and from those lists to the logs that use them.
+type acceptedRootsIndex struct {
+	acceptedRootsByRootsList                                       map[[sha256.Size]byte][]AcceptedRoot
+	rootsListsByFingerprint, rootsListsBySPKIHash, logsByRootsList map[[sha256.Size]byte][][sha256.Size]byte
+}
+
+// Describing every root is comparatively expensive, so the index is built on first access after each LoadAcceptedRoots, which discards it.
+// The mutex only serializes building the index: the index is built from AcceptedRootsMap and LogAcceptedRootsMap without locking them, so (as for the rest of the Accepted Roots) LoadAcceptedRoots must not run concurrently with any function that reads them.
+var acceptedRootsIndexMutex sync.Mutex
+var currentAcceptedRootsIndex *acceptedRootsIndex
+
+// getAcceptedRootsIndex returns the index of the loaded Accepted Roots, building it if necessary.
+func getAcceptedRootsIndex() *acceptedRootsIndex {
+	acceptedRootsIndexMutex.Lock()
+	defer acceptedRootsIndexMutex.Unlock()
+	if currentAcceptedRootsIndex == nil {
+		currentAcceptedRootsIndex = buildAcceptedRootsIndex()
+	}
+	return currentAcceptedRootsIndex
+}
+
+// resetAcceptedRootsIndex discards the index, so that it is rebuilt on next access.
+func resetAcceptedRootsIndex() {
+	acceptedRootsIndexMutex.Lock()
+	currentAcceptedRootsIndex = nil
+	acceptedRootsIndexMutex.Unlock()
+}
+
+func buildAcceptedRootsIndex() *acceptedRootsIndex {
+	index := &acceptedRootsIndex{
+		acceptedRootsByRootsList: make(map[[sha256.Size]byte][]AcceptedRoot),
+		rootsListsByFingerprint:  make(map[[sha256.Size]byte][][sha256.Size]byte),
+		rootsListsBySPKIHash:     make(map[[sha256.Size]byte][][sha256.Size]byte),
+		logsByRootsList:          make(map[[sha256.Size]byte][][sha256.Size]byte),
+	}
+
+	// Each root is typically included in many lists, so only describe it once.
+	acceptedRoots := make(map[[sha256.Size]byte]AcceptedRoot)
+	for rootsListHash, pool := range AcceptedRootsMap {
+		for _, root := range pool.RawCertificates() {
+			fingerprint := sha256.Sum256(root.Raw)
+			acceptedRoot, ok := acceptedRoots[fingerprint]
+			if !ok {
+				acceptedRoot = newAcceptedRoot(root)
+				acceptedRoots[fingerprint] = acceptedRoot
+			}
+			index.acceptedRootsByRootsList[rootsListHash] = append(index.acceptedRootsByRootsList[rootsListHash], acceptedRoot)
+
+			if !slices.Contains(index.rootsListsByFingerprint[fingerprint], rootsListHash) {
+				index.rootsListsByFingerprint[fingerprint] = append(index.rootsListsByFingerprint[fingerprint], rootsListHash)
+			}
+			if !slices.Contains(index.rootsListsBySPKIHash[acceptedRoot.SPKIHash], rootsListHash) {
+				index.rootsListsBySPKIHash[acceptedRoot.SPKIHash] = append(index.rootsListsBySPKIHash[acceptedRoot.SPKIHash], rootsListHash)
+			}
+		}
+	}
+	for logID, rootsListHash := range LogAcceptedRootsMap {
+		index.logsByRootsList[rootsListHash] = append(index.logsByRootsList[rootsListHash], logID)
+	}
+	return index
+}
+
+func newAcceptedRoot(cert *x509.Certificate) AcceptedRoot {
+	acceptedRoot := AcceptedRoot{
+		DER:         cert.Raw,
+		Fingerprint: sha256.Sum256(cert.Raw),
+		SPKIHash:    sha256.Sum256(cert.RawSubjectPublicKeyInfo),
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		SelfSigned:  bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil,
+	}
+	switch publicKey := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		acceptedRoot.KeyAlgorithm = fmt.Sprintf("RSA %d", publicKey.N.BitLen())
+	case *ecdsa.PublicKey:
+		acceptedRoot.KeyAlgorithm = "ECDSA " + publicKey.Curve.Params().Name
+	default:
+		acceptedRoot.KeyAlgorithm = cert.PublicKeyAlgorithm.String()
+	}
+	return acceptedRoot
+}
+
+// AcceptedRootsForLog returns the Accepted Roots of the log identified by logID, or nil if they are not known.
+// Logs with identical Accepted Roots lists share the returned slice, so callers must not modify it.
+func AcceptedRootsForLog(logID [sha256.Size]byte) []AcceptedRoot {
+	rootsListHash, ok := LogAcceptedRootsMap[logID]
+	if !ok {
+		return nil
+	}
+	return getAcceptedRootsIndex().acceptedRootsByRootsList[rootsListHash]
+}
+
+// LogsAcceptingRoot returns the IDs, in ascending order, of the logs whose Accepted Roots include a root certificate with the given SHA-256 fingerprint or SHA-256 SPKI hash.
+func LogsAcceptingRoot(fp [sha256.Size]byte) [][sha256.Size]byte {
+	index := getAcceptedRootsIndex()
+	var logIDs [][sha256.Size]byte
+	for _, rootsListHash := range append(slices.Clone(index.rootsListsByFingerprint[fp]), index.rootsListsBySPKIHash[fp]...) {
+		for _, logID := range index.logsByRootsList[rootsListHash] {
+			if !slices.Contains(logIDs, logID) {
+				logIDs = append(logIDs, logID)
+			}
+		}
+	}
+	slices.SortFunc(logIDs, func(a, b [sha256.Size]byte) int {
+		return bytes.Compare(a[:], b[:])
+	})
+	return logIDs
+}
+
+// ErrChainNotAccepted is wrapped by the error returned by VerifyChain when a chain does not lead to one of the roots.
+var ErrChainNotAccepted = errors.New("chain does not lead to an accepted root")
+
+// VerifyChain checks that chain leads to one of roots, ignoring expiry, EKUs, name constraints and critical extensions (e.g. the precertificate poison) in the way that CT logs do. It returns the verified chain, including the root.
+// chain[0] is the end-entity certificate or precertificate; the remaining certificates are treated as intermediates, and may include the root.
+func VerifyChain(roots *x509util.PEMCertPool, chain []*x509.Certificate) ([]*x509.Certificate, error) {
+	if err := checkChain(chain); err != nil {
+		return nil, err
+	} else if roots == nil {
+		return nil, fmt.Errorf("no accepted roots")
+	}
+
+	// A chain may consist only of an accepted root.
+	if len(chain) == 1 && roots.Included(chain[0]) {
+		return chain, nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:                          roots.CertPool(),
+		Intermediates:                  intermediates,
+		DisableTimeChecks:              true,
+		DisableCriticalExtensionChecks: true,
+		DisableNameChecks:              true,
+		DisableEKUChecks:               true,
+		DisablePathLenChecks:           true,
+		DisableNameConstraintChecks:    true,
+		KeyUsages:                      []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrChainNotAccepted, err)
+	}
+	return chains[0], nil
+}
+
+// checkChain returns an error if chain is malformed, and so couldn't be accepted by any log.
+func checkChain(chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("empty chain")
+	} else if slices.Contains(chain, nil) {
+		return fmt.Errorf("nil certificate in chain")
+	}
+	return nil
+}
+
+// AcceptsChain returns true if the log identified by logID would accept chain, according to its Accepted Roots, or false if chain does not lead to one of them.
+// An error is returned if the log's Accepted Roots are not known or chain is malformed (e.g. empty), rather than merely not accepted.
+func AcceptsChain(logID [sha256.Size]byte, chain []*x509.Certificate) (bool, error) {
+	rootsListHash, ok := LogAcceptedRootsMap[logID]
+	if !ok || AcceptedRootsMap[rootsListHash] == nil {
+		return false, fmt.Errorf("no accepted roots known for log %x", logID)
+	}
+	if _, err := VerifyChain(AcceptedRootsMap[rootsListHash], chain); errors.Is(err, ErrChainNotAccepted) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// LogsAcceptingChain returns the subset of the named log list containing the logs that would accept chain, according to their Accepted Roots.
+// Logs whose Accepted Roots are not known are excluded. An error is returned if chain is malformed (e.g. empty).
+func LogsAcceptingChain(chain []*x509.Certificate, listName string) (*loglist3.LogList, error) {
+	logList := LogListByName(listName)
+	if logList == nil {
+		return nil, fmt.Errorf("unknown log list %q", listName)
+	} else if err := checkChain(chain); err != nil {
+		return nil, err
+	}
+
+	// Many logs share the same Accepted Roots list, so only verify the chain once per list.
+	acceptedByRootsList := make(map[[sha256.Size]byte]bool)
+	accepts := func(logIDBytes []byte) bool {
+		rootsListHash, ok := LogAcceptedRootsMap[toLogID(logIDBytes)]
+		if !ok || AcceptedRootsMap[rootsListHash] == nil {
+			return false
+		}
+		if accepted, ok := acceptedByRootsList[rootsListHash]; ok {
+			return accepted
+		}
+		_, err := VerifyChain(AcceptedRootsMap[rootsListHash], chain)
+		acceptedByRootsList[rootsListHash] = err == nil
+		return err == nil
+	}
+
+	var accepting loglist3.LogList
+	for _, operator := range logList.Operators {
+		acceptingOp := *operator
+		acceptingOp.Logs = []*loglist3.Log{}
+		for _, log := range operator.Logs {
+			if accepts(log.LogID) {
+				acceptingOp.Logs = append(acceptingOp.Logs, log)
+			}
+		}
+		acceptingOp.TiledLogs = []*loglist3.TiledLog{}
+		for _, tiledLog := range operator.TiledLogs {
+			if accepts(tiledLog.LogID) {
+				acceptingOp.TiledLogs = append(acceptingOp.TiledLogs, tiledLog)
+			}
+		}
+		if len(acceptingOp.Logs) > 0 || len(acceptingOp.TiledLogs) > 0 {
+			accepting.Operators = append(accepting.Operators, &acceptingOp)
+		}
+	}
+	return &accepting, nil
+}
+
+// rootCertificateCache parses each distinct DER-encoded root certificate only once, so that every Accepted Roots list that includes a root shares the same *x509.Certificate.
+type rootCertificateCache map[[sha256.Size]byte]*parsedRoot
+
+type parsedRoot struct {
+	cert *x509.Certificate
+	err  error
+}
+
+func (c rootCertificateCache) certificate(der []byte) (*x509.Certificate, error) {
+	fingerprint := sha256.Sum256(der)
+	if pr, ok := c[fingerprint]; ok {
+		return pr.cert, pr.err
+	}
+	pr := &parsedRoot{}
+	if pr.cert, pr.err = x509.ParseCertificate(der); !x509.IsFatal(pr.err) {
+		pr.err = nil
+	} else {
+		pr.cert = nil
+	}
+	c[fingerprint] = pr
+	return pr.cert, pr.err
+}
+
+// unparseableRoot describes the certificate at the specified index in a roots_<hash>.pem file, which could not be parsed.
+func unparseableRoot(source string, index int, der []byte, err error) QuarantinedRoot {
+	return QuarantinedRoot{Source: source, Index: index, Raw: der, Error: err.Error()}
+}
+
+// readQuarantineFile reads the certificates that the acceptedroots collector quarantined from the Accepted Roots of the log identified by logID.
+func readQuarantineFile(fsys fs.FS, name string, logID [sha256.Size]byte) ([]QuarantinedRoot, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var quarantined []QuarantinedRoot
+	if err = json.Unmarshal(data, &quarantined); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path.Base(name), err)
+	}
+	for i := range quarantined {
+		quarantined[i].LogID = logID
+	}
+	return quarantined, nil
+}
@@ -0,0 +1,96 @@
+package ctloglists
+
+import (
+	"crypto/sha256"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestLoadAcceptedRootsAgain(t *testing.T) {
+	if err := LoadAcceptedRoots(); err != nil {
+		t.Fatal(err)
+	}
+	logAcceptedRoots, endpointTypes, quarantined := len(LogAcceptedRootsMap), len(LogEndpointTypeMap), len(QuarantinedRootsMap)
+	if logAcceptedRoots == 0 || endpointTypes == 0 {
+		t.Fatalf("loaded %d logs' Accepted Roots and %d endpoint types", logAcceptedRoots, endpointTypes)
+	}
+
+	// Entries that aren't in the bundled files mustn't survive another load.
+	stale := sha256.Sum256([]byte("stale"))
+	LogAcceptedRootsMap[stale] = stale
+	LogEndpointTypeMap[stale] = EndpointTypeRFC6962
+	QuarantinedRootsMap[stale] = []QuarantinedRoot{{LogID: stale, Source: "get-roots"}}
+	if err := LoadAcceptedRoots(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := LogAcceptedRootsMap[stale]; ok {
+		t.Errorf("LogAcceptedRootsMap kept an entry from before the load")
+	}
+	if _, ok := LogEndpointTypeMap[stale]; ok {
+		t.Errorf("LogEndpointTypeMap kept an entry from before the load")
+	}
+	if _, ok := QuarantinedRootsMap[stale]; ok {
+		t.Errorf("QuarantinedRootsMap kept an entry from before the load")
+	}
+	if len(LogAcceptedRootsMap) != logAcceptedRoots || len(LogEndpointTypeMap) != endpointTypes || len(QuarantinedRootsMap) != quarantined {
+		t.Errorf("loading again changed the number of entries")
+	}
+}
+
+// TestAcceptedRootsIndexConcurrency exercises building and discarding the Accepted Roots index from several goroutines at once, for the race detector.
+func TestAcceptedRootsIndexConcurrency(t *testing.T) {
+	if err := LoadAcceptedRoots(); err != nil {
+		t.Fatal(err)
+	}
+	var logID [sha256.Size]byte
+	for logID = range LogAcceptedRootsMap {
+		break
+	}
+	want := len(AcceptedRootsForLog(logID))
+	if want == 0 {
+		t.Fatalf("no Accepted Roots for log %x", logID)
+	}
+	fingerprint := AcceptedRootsForLog(logID)[0].Fingerprint
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := len(AcceptedRootsForLog(logID)); got != want {
+				t.Errorf("AcceptedRootsForLog returned %d roots, want %d", got, want)
+			}
+			resetAcceptedRootsIndex()
+			if len(LogsAcceptingRoot(fingerprint)) == 0 {
+				t.Errorf("LogsAcceptingRoot returned no logs")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// BenchmarkLoadAcceptedRoots reports the heap retained by the loaded Accepted Roots, measured after a forced GC, both before and after the index used by AcceptedRootsForLog and LogsAcceptingRoot is built.
+func BenchmarkLoadAcceptedRoots(b *testing.B) {
+	heapInUse := func() float64 {
+		runtime.GC()
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		return float64(memStats.HeapAlloc)
+	}
+	AcceptedRootsMap, LogAcceptedRootsMap = nil, nil
+	resetAcceptedRootsIndex()
+	baseline := heapInUse()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := LoadAcceptedRoots(); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+	b.ReportMetric((heapInUse()-baseline)/(1<<20), "retained-MiB")
+	getAcceptedRootsIndex()
+	b.ReportMetric((heapInUse()-baseline)/(1<<20), "retained-indexed-MiB")
+}
@@ -0,0 +1,168 @@
+package ctloglists
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// AcceptedRootsSet maps log IDs to those logs' Accepted Roots.
+type AcceptedRootsSet map[[sha256.Size]byte][]AcceptedRoot
+
+// AcceptedRootsChange describes the roots added to and removed from one log's Accepted Roots, and the certificates newly quarantined from them.
+type AcceptedRootsChange struct {
+	LogID       [sha256.Size]byte
+	Added       []AcceptedRoot
+	Removed     []AcceptedRoot
+	Quarantined []QuarantinedRoot
+}
+
+// ReadAcceptedRoots reads an Accepted Roots directory (with the same layout as files/acceptedroots) from fsys.
+// As in LoadAcceptedRoots, certificates that can't be parsed are excluded from the logs' Accepted Roots. They are returned as quarantined, keyed by log ID, along with those recorded in log_<id>.quarantine files.
+func ReadAcceptedRoots(fsys fs.FS) (AcceptedRootsSet, map[[sha256.Size]byte][]QuarantinedRoot, error) {
+	dirEntry, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Read the Accepted Roots lists. Each root is typically included in many lists, so only parse and describe it once.
+	rootsLists := make(map[string][]AcceptedRoot)
+	quarantinedByRootsList := make(map[string][]QuarantinedRoot)
+	sharedRoots := make(rootCertificateCache)
+	describedRoots := make(map[[sha256.Size]byte]AcceptedRoot)
+	for _, file := range dirEntry {
+		if !strings.HasPrefix(file.Name(), "roots_") || !strings.HasSuffix(file.Name(), ".pem") {
+			continue
+		}
+		rootsListHash := strings.TrimSuffix(strings.TrimPrefix(file.Name(), "roots_"), ".pem")
+		pemData, err := fs.ReadFile(fsys, file.Name())
+		if err != nil {
+			return nil, nil, err
+		}
+		var roots []AcceptedRoot
+		index := 0
+		for block, rest := pem.Decode(pemData); block != nil; block, rest = pem.Decode(rest) {
+			cert, err := sharedRoots.certificate(block.Bytes)
+			if err != nil {
+				quarantinedByRootsList[rootsListHash] = append(quarantinedByRootsList[rootsListHash], unparseableRoot(file.Name(), index, block.Bytes, err))
+			} else {
+				root, ok := describedRoots[sha256.Sum256(cert.Raw)]
+				if !ok {
+					root = newAcceptedRoot(cert)
+					describedRoots[root.Fingerprint] = root
+				}
+				roots = append(roots, root)
+			}
+			index++
+		}
+		rootsLists[rootsListHash] = roots
+	}
+
+	// Read the mapping of log IDs to Accepted Roots list hashes, and the certificates quarantined by the acceptedroots collector.
+	acceptedRoots := make(AcceptedRootsSet)
+	quarantined := make(map[[sha256.Size]byte][]QuarantinedRoot)
+	for _, file := range dirEntry {
+		hexLogID, ok := strings.CutPrefix(file.Name(), "log_")
+		if !ok {
+			continue
+		}
+		hexLogID, extension, _ := strings.Cut(hexLogID, ".")
+		if extension != "txt" && extension != "quarantine" {
+			continue
+		}
+		decodedLogID, err := hex.DecodeString(hexLogID)
+		if err != nil || len(decodedLogID) != sha256.Size {
+			return nil, nil, fmt.Errorf("invalid log ID in filename %s", file.Name())
+		}
+		logID := toLogID(decodedLogID)
+
+		if extension == "quarantine" {
+			collectorQuarantined, err := readQuarantineFile(fsys, file.Name(), logID)
+			if err != nil {
+				return nil, nil, err
+			}
+			quarantined[logID] = append(quarantined[logID], collectorQuarantined...)
+			continue
+		}
+		data, err := fs.ReadFile(fsys, file.Name())
+		if err != nil {
+			return nil, nil, err
+		}
+		rootsListHash := strings.TrimSpace(string(data))
+		roots, ok := rootsLists[rootsListHash]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s refers to a missing roots list", file.Name())
+		}
+		acceptedRoots[logID] = roots
+		for _, qr := range quarantinedByRootsList[rootsListHash] {
+			qr.LogID = logID
+			quarantined[logID] = append(quarantined[logID], qr)
+		}
+	}
+
+	return acceptedRoots, quarantined, nil
+}
+
+// DiffAcceptedRoots compares two sets of Accepted Roots, returning (in ascending log ID order) the changes to each log's Accepted Roots.
+// A log present in only one set is reported as having had all of its roots added or removed.
+// oldQuarantined and newQuarantined (as returned by ReadAcceptedRoots, or nil) are compared too, so that certificates newly excluded from a log's Accepted Roots because they couldn't be parsed are reported rather than silently dropped.
+func DiffAcceptedRoots(oldRoots, newRoots AcceptedRootsSet, oldQuarantined, newQuarantined map[[sha256.Size]byte][]QuarantinedRoot) []AcceptedRootsChange {
+	logIDs := make(map[[sha256.Size]byte]bool)
+	for logID := range oldRoots {
+		logIDs[logID] = true
+	}
+	for logID := range newRoots {
+		logIDs[logID] = true
+	}
+	for logID := range newQuarantined {
+		logIDs[logID] = true
+	}
+	var changes []AcceptedRootsChange
+	for logID := range logIDs {
+		if change := diffRoots(logID, oldRoots[logID], newRoots[logID], oldQuarantined[logID], newQuarantined[logID]); change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	slices.SortFunc(changes, func(a, b AcceptedRootsChange) int {
+		return bytes.Compare(a.LogID[:], b.LogID[:])
+	})
+	return changes
+}
+
+func diffRoots(logID [sha256.Size]byte, oldRoots, newRoots []AcceptedRoot, oldQuarantined, newQuarantined []QuarantinedRoot) *AcceptedRootsChange {
+	change := AcceptedRootsChange{LogID: logID}
+	oldFingerprints := make(map[[sha256.Size]byte]bool)
+	for _, root := range oldRoots {
+		oldFingerprints[root.Fingerprint] = true
+	}
+	newFingerprints := make(map[[sha256.Size]byte]bool)
+	for _, root := range newRoots {
+		newFingerprints[root.Fingerprint] = true
+		if !oldFingerprints[root.Fingerprint] {
+			change.Added = append(change.Added, root)
+		}
+	}
+	for _, root := range oldRoots {
+		if !newFingerprints[root.Fingerprint] {
+			change.Removed = append(change.Removed, root)
+		}
+	}
+	oldQuarantinedHashes := make(map[[sha256.Size]byte]bool)
+	for _, qr := range oldQuarantined {
+		oldQuarantinedHashes[sha256.Sum256(qr.Raw)] = true
+	}
+	for _, qr := range newQuarantined {
+		if !oldQuarantinedHashes[sha256.Sum256(qr.Raw)] {
+			change.Quarantined = append(change.Quarantined, qr)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 && len(change.Quarantined) == 0 {
+		return nil
+	}
+	return &change
+}
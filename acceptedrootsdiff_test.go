@@ -0,0 +1,87 @@
+package ctloglists_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+	"testing/fstest"
+
+	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/ctloglisttest"
+)
+
+// acceptedRootsDir returns an Accepted Roots directory in which the log identified by logID accepts the certificates in pemBlocks, and whose collector quarantined those in quarantine (if not empty).
+func acceptedRootsDir(logID [sha256.Size]byte, quarantine string, pemBlocks ...[]byte) fstest.MapFS {
+	var pemData []byte
+	for _, block := range pemBlocks {
+		pemData = append(pemData, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: block})...)
+	}
+	rootsListHash := sha256.Sum256(pemData)
+	fsys := fstest.MapFS{
+		"roots_" + hex.EncodeToString(rootsListHash[:]) + ".pem": {Data: pemData},
+		"log_" + hex.EncodeToString(logID[:]) + ".txt":           {Data: []byte(hex.EncodeToString(rootsListHash[:]) + "\n")},
+	}
+	if quarantine != "" {
+		fsys["log_"+hex.EncodeToString(logID[:])+".quarantine"] = &fstest.MapFile{Data: []byte(quarantine)}
+	}
+	return fsys
+}
+
+func TestReadAcceptedRoots(t *testing.T) {
+	root, err := ctloglisttest.NewRootCA("Test Root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRoot, err := ctloglisttest.NewRootCA("Other Test Root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logID := sha256.Sum256([]byte("test log"))
+	garbage := []byte("not a certificate")
+
+	oldRoots, oldQuarantined, err := ctloglists.ReadAcceptedRoots(acceptedRootsDir(logID, "", root.Certificate.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(oldRoots[logID]) != 1 || oldRoots[logID][0].Fingerprint != sha256.Sum256(root.Certificate.Raw) {
+		t.Errorf("ReadAcceptedRoots returned %d roots, want only the test root", len(oldRoots[logID]))
+	}
+	if len(oldQuarantined) != 0 {
+		t.Errorf("ReadAcceptedRoots quarantined %d logs' certificates, want none", len(oldQuarantined))
+	}
+
+	// An unparseable certificate is quarantined rather than silently dropped, as are those quarantined by the collector.
+	newRoots, newQuarantined, err := ctloglists.ReadAcceptedRoots(acceptedRootsDir(logID, `[{"source":"get-roots","index":3,"raw":"AAAA","error":"bad"}]`, garbage, otherRoot.Certificate.Raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newRoots[logID]) != 1 || newRoots[logID][0].Fingerprint != sha256.Sum256(otherRoot.Certificate.Raw) {
+		t.Errorf("ReadAcceptedRoots returned %d roots, want only the other test root", len(newRoots[logID]))
+	}
+	if quarantined := newQuarantined[logID]; len(quarantined) != 2 {
+		t.Fatalf("ReadAcceptedRoots quarantined %d certificates, want 2", len(quarantined))
+	}
+	for _, qr := range newQuarantined[logID] {
+		if qr.LogID != logID || qr.Error == "" {
+			t.Errorf("quarantined %s #%d has log ID %x and error %q", qr.Source, qr.Index, qr.LogID, qr.Error)
+		}
+	}
+
+	changes := ctloglists.DiffAcceptedRoots(oldRoots, newRoots, oldQuarantined, newQuarantined)
+	if len(changes) != 1 {
+		t.Fatalf("DiffAcceptedRoots returned %d changes, want 1", len(changes))
+	}
+	if change := changes[0]; len(change.Added) != 1 || len(change.Removed) != 1 || len(change.Quarantined) != 2 {
+		t.Errorf("DiffAcceptedRoots reported %d added, %d removed and %d quarantined, want 1, 1 and 2", len(change.Added), len(change.Removed), len(change.Quarantined))
+	}
+
+	// Certificates that were already quarantined aren't reported again.
+	if changes := ctloglists.DiffAcceptedRoots(newRoots, newRoots, newQuarantined, newQuarantined); len(changes) != 0 {
+		t.Errorf("DiffAcceptedRoots returned %d changes between identical sets, want 0", len(changes))
+	}
+
+	if _, _, err := ctloglists.ReadAcceptedRoots(acceptedRootsDir(logID, "not JSON", root.Certificate.Raw)); err == nil {
+		t.Errorf("ReadAcceptedRoots accepted a malformed quarantine file")
+	}
+}
@@ -0,0 +1,110 @@
+package ctloglists
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+const acceptedRootsHistoryFilename = "files/acceptedrootshistory/history.json"
+
+// AcceptedRootsHistory records the periods during which each log's Accepted Roots included each root, as derived from this repository's git history by cmd/acceptedrootshistory.
+// Logs and Roots are append-only, so that the indexes into them used by Periods remain stable as the history grows.
+type AcceptedRootsHistory struct {
+	// Revision is the last git commit that the history was derived from, and RevisionTime is its commit time.
+	Revision     string                       `json:"revision"`
+	RevisionTime time.Time                    `json:"revision_time"`
+	Logs         []string                     `json:"logs"`  // Hex-encoded log IDs.
+	Roots        []string                     `json:"roots"` // Hex-encoded SHA-256 root certificate fingerprints.
+	Periods      []AcceptedRootsHistoryPeriod `json:"periods"`
+}
+
+// AcceptedRootsHistoryPeriod records that the log with index Log accepted the roots with indexes Roots from FirstSeen until LastSeen.
+type AcceptedRootsHistoryPeriod struct {
+	Log       int        `json:"log"`
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  *time.Time `json:"last_seen,omitempty"` // nil if the roots were still accepted as of the history's Revision.
+	Roots     []int      `json:"roots"`
+}
+
+// RootAcceptancePeriod is a period during which a log accepted a root.
+// FirstSeen and LastSeen are the commit times of the first and last snapshots of the log's Accepted Roots that included the root; LastSeen is zero if the root was still accepted as of the latest snapshot.
+type RootAcceptancePeriod struct {
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// RootAcceptanceHistoryMap maps log IDs to root fingerprints to the periods during which the log accepted the root.
+var RootAcceptanceHistoryMap map[[sha256.Size]byte]map[[sha256.Size]byte][]RootAcceptancePeriod
+
+// LoadAcceptedRootsHistory loads and parses the bundled Accepted Roots history. It is not an error for there to be no bundled history.
+func LoadAcceptedRootsHistory() error {
+	RootAcceptanceHistoryMap = make(map[[sha256.Size]byte]map[[sha256.Size]byte][]RootAcceptancePeriod)
+
+	data, err := files.ReadFile(acceptedRootsHistoryFilename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var history AcceptedRootsHistory
+	if err = json.Unmarshal(data, &history); err != nil {
+		return err
+	}
+
+	logIDs := make([][sha256.Size]byte, len(history.Logs))
+	for i, logID := range history.Logs {
+		if logIDs[i], err = decodeHexHash(logID); err != nil {
+			return fmt.Errorf("invalid log ID %q in Accepted Roots history: %v", logID, err)
+		}
+	}
+	fingerprints := make([][sha256.Size]byte, len(history.Roots))
+	for i, fingerprint := range history.Roots {
+		if fingerprints[i], err = decodeHexHash(fingerprint); err != nil {
+			return fmt.Errorf("invalid root fingerprint %q in Accepted Roots history: %v", fingerprint, err)
+		}
+	}
+
+	for _, period := range history.Periods {
+		if period.Log < 0 || period.Log >= len(logIDs) {
+			return fmt.Errorf("invalid log index %d in Accepted Roots history", period.Log)
+		}
+		rap := RootAcceptancePeriod{FirstSeen: period.FirstSeen}
+		if period.LastSeen != nil {
+			rap.LastSeen = *period.LastSeen
+		}
+		logID := logIDs[period.Log]
+		if RootAcceptanceHistoryMap[logID] == nil {
+			RootAcceptanceHistoryMap[logID] = make(map[[sha256.Size]byte][]RootAcceptancePeriod)
+		}
+		for _, root := range period.Roots {
+			if root < 0 || root >= len(fingerprints) {
+				return fmt.Errorf("invalid root index %d in Accepted Roots history", root)
+			}
+			RootAcceptanceHistoryMap[logID][fingerprints[root]] = append(RootAcceptanceHistoryMap[logID][fingerprints[root]], rap)
+		}
+	}
+
+	return nil
+}
+
+// RootAcceptanceHistory returns the periods, in chronological order, during which the log identified by logID accepted the root with SHA-256 fingerprint fp.
+func RootAcceptanceHistory(logID, fp [sha256.Size]byte) []RootAcceptancePeriod {
+	return RootAcceptanceHistoryMap[logID][fp]
+}
+
+func decodeHexHash(s string) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return hash, err
+	} else if len(decoded) != sha256.Size {
+		return hash, fmt.Errorf("expected %d bytes, got %d", sha256.Size, len(decoded))
+	}
+	copy(hash[:], decoded)
+	return hash, nil
+}
@@ -0,0 +1,174 @@
+package ctloglists_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/ctloglisttest"
+
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+// testPKI holds a root with an intermediate, the certificates and precertificates they issued, and an untrusted root with a certificate it issued.
+type testPKI struct {
+	root, intermediate, untrustedRoot                                   *ctloglisttest.CA
+	cert, precert, intermediateCert, intermediatePrecert, untrustedCert *x509.Certificate
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	var pki testPKI
+	var err error
+	check := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pki.root, err = ctloglisttest.NewRootCA("Test Root")
+	check(err)
+	pki.intermediate, err = pki.root.NewIntermediateCA("Test Intermediate")
+	check(err)
+	pki.untrustedRoot, err = ctloglisttest.NewRootCA("Untrusted Root")
+	check(err)
+	pki.cert, err = pki.root.IssueCertificate("root.example.com")
+	check(err)
+	pki.precert, err = pki.root.IssuePrecertificate("root.example.com")
+	check(err)
+	pki.intermediateCert, err = pki.intermediate.IssueCertificate("intermediate.example.com")
+	check(err)
+	pki.intermediatePrecert, err = pki.intermediate.IssuePrecertificate("intermediate.example.com")
+	check(err)
+	pki.untrustedCert, err = pki.untrustedRoot.IssueCertificate("untrusted.example.com")
+	check(err)
+	return pki
+}
+
+func (pki testPKI) roots() *x509util.PEMCertPool {
+	pool := x509util.NewPEMCertPool()
+	pool.AddCert(pki.root.Certificate)
+	return pool
+}
+
+func TestVerifyChain(t *testing.T) {
+	pki := newTestPKI(t)
+	for _, test := range []struct {
+		name      string
+		chain     []*x509.Certificate
+		wantLen   int   // The length of the verified chain, including the root.
+		wantErr   error // Wrapped by the error, if not nil.
+		malformed bool
+	}{
+		{name: "certificate", chain: []*x509.Certificate{pki.cert}, wantLen: 2},
+		{name: "certificate with root", chain: []*x509.Certificate{pki.cert, pki.root.Certificate}, wantLen: 2},
+		{name: "precertificate", chain: []*x509.Certificate{pki.precert, pki.root.Certificate}, wantLen: 2},
+		{name: "via intermediate", chain: []*x509.Certificate{pki.intermediateCert, pki.intermediate.Certificate}, wantLen: 3},
+		{name: "precertificate via intermediate", chain: []*x509.Certificate{pki.intermediatePrecert, pki.intermediate.Certificate, pki.root.Certificate}, wantLen: 3},
+		{name: "root alone", chain: []*x509.Certificate{pki.root.Certificate}, wantLen: 1},
+		{name: "missing intermediate", chain: []*x509.Certificate{pki.intermediateCert}, wantErr: ctloglists.ErrChainNotAccepted},
+		{name: "untrusted root", chain: []*x509.Certificate{pki.untrustedCert, pki.untrustedRoot.Certificate}, wantErr: ctloglists.ErrChainNotAccepted},
+		{name: "untrusted root alone", chain: []*x509.Certificate{pki.untrustedRoot.Certificate}, wantErr: ctloglists.ErrChainNotAccepted},
+		{name: "empty chain", malformed: true},
+		{name: "nil certificate", chain: []*x509.Certificate{pki.cert, nil}, malformed: true},
+	} {
+		verified, err := ctloglists.VerifyChain(pki.roots(), test.chain)
+		switch {
+		case test.malformed:
+			if err == nil || errors.Is(err, ctloglists.ErrChainNotAccepted) {
+				t.Errorf("%s: err = %v, want a malformed chain error", test.name, err)
+			}
+		case test.wantErr != nil:
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("%s: err = %v, want %v", test.name, err, test.wantErr)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case len(verified) != test.wantLen || !verified[len(verified)-1].Equal(pki.root.Certificate):
+			t.Errorf("%s: verified chain of %d certificates, want %d ending at the root", test.name, len(verified), test.wantLen)
+		}
+	}
+
+	if _, err := ctloglists.VerifyChain(nil, []*x509.Certificate{pki.cert}); err == nil || errors.Is(err, ctloglists.ErrChainNotAccepted) {
+		t.Errorf("VerifyChain with no roots: err = %v", err)
+	}
+}
+
+// useTestRoots points the first log in Chrome's log list at an Accepted Roots list containing only pki's root, until the test finishes.
+func useTestRoots(t *testing.T, pki testPKI) [sha256.Size]byte {
+	t.Helper()
+	if err := ctloglists.LoadLogLists(); err != nil {
+		t.Fatal(err)
+	} else if err = ctloglists.LoadAcceptedRoots(); err != nil {
+		t.Fatal(err)
+	}
+	logID := sha256.Sum256(ctloglists.GstaticV3All.Operators[0].Logs[0].Key)
+	rootsListHash := sha256.Sum256(pki.root.Certificate.Raw)
+	previous, hadPrevious := ctloglists.LogAcceptedRootsMap[logID]
+	ctloglists.AcceptedRootsMap[rootsListHash] = pki.roots()
+	ctloglists.LogAcceptedRootsMap[logID] = rootsListHash
+	t.Cleanup(func() {
+		delete(ctloglists.AcceptedRootsMap, rootsListHash)
+		if hadPrevious {
+			ctloglists.LogAcceptedRootsMap[logID] = previous
+		} else {
+			delete(ctloglists.LogAcceptedRootsMap, logID)
+		}
+	})
+	return logID
+}
+
+func TestAcceptsChain(t *testing.T) {
+	pki := newTestPKI(t)
+	logID := useTestRoots(t, pki)
+
+	for _, test := range []struct {
+		name  string
+		chain []*x509.Certificate
+		want  bool
+	}{
+		{"certificate", []*x509.Certificate{pki.cert}, true},
+		{"via intermediate", []*x509.Certificate{pki.intermediateCert, pki.intermediate.Certificate}, true},
+		{"untrusted root", []*x509.Certificate{pki.untrustedCert, pki.untrustedRoot.Certificate}, false},
+	} {
+		if accepted, err := ctloglists.AcceptsChain(logID, test.chain); err != nil || accepted != test.want {
+			t.Errorf("%s: AcceptsChain = %v, %v, want %v, nil", test.name, accepted, err, test.want)
+		}
+	}
+
+	if _, err := ctloglists.AcceptsChain(logID, nil); err == nil {
+		t.Errorf("AcceptsChain accepted an empty chain without error")
+	}
+	if _, err := ctloglists.AcceptsChain(sha256.Sum256([]byte("unknown log")), []*x509.Certificate{pki.cert}); err == nil {
+		t.Errorf("AcceptsChain didn't return an error for a log with unknown Accepted Roots")
+	}
+}
+
+func TestLogsAcceptingChain(t *testing.T) {
+	pki := newTestPKI(t)
+	logID := useTestRoots(t, pki)
+
+	accepting, err := ctloglists.LogsAcceptingChain([]*x509.Certificate{pki.intermediateCert, pki.intermediate.Certificate}, "gstatic-all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accepting.Operators) != 1 || len(accepting.Operators[0].Logs) != 1 || len(accepting.Operators[0].TiledLogs) != 0 || sha256.Sum256(accepting.Operators[0].Logs[0].Key) != logID {
+		t.Errorf("LogsAcceptingChain returned %d operators, want only the test log's", len(accepting.Operators))
+	} else if accepting.Operators[0].Name != ctloglists.GstaticV3All.Operators[0].Name {
+		t.Errorf("LogsAcceptingChain returned operator %q, want %q", accepting.Operators[0].Name, ctloglists.GstaticV3All.Operators[0].Name)
+	}
+
+	if accepting, err = ctloglists.LogsAcceptingChain([]*x509.Certificate{pki.untrustedCert, pki.untrustedRoot.Certificate}, "gstatic-all"); err != nil {
+		t.Fatal(err)
+	} else if len(accepting.Operators) != 0 {
+		t.Errorf("LogsAcceptingChain returned %d operators for an untrusted chain, want 0", len(accepting.Operators))
+	}
+
+	if _, err = ctloglists.LogsAcceptingChain(nil, "gstatic-all"); err == nil {
+		t.Errorf("LogsAcceptingChain accepted an empty chain without error")
+	}
+	if _, err = ctloglists.LogsAcceptingChain([]*x509.Certificate{pki.cert}, "no-such-list"); err == nil {
+		t.Errorf("LogsAcceptingChain accepted an unknown log list name")
+	}
+}
@@ -4,15 +4,13 @@ CWD=`pwd`
 SCRIPT_DIR=`cd -- "$( dirname -- "${BASH_SOURCE[0]}" )" &> /dev/null && pwd`
 cd $SCRIPT_DIR
 
-go run main.go
-
 mkdir -p ../../files/acceptedroots
+go run main.go ../../files/acceptedroots
+EXIT_CODE=$?
+
 cd ../../files/acceptedroots
-git restore --staged *.pem *.txt
-git rm -f *.pem *.txt
-rm -f *.pem *.txt
-mv $SCRIPT_DIR/*.pem .
-mv $SCRIPT_DIR/*.txt .
-git add -v *.pem *.txt
+git restore --staged .
+git add -A -v .
 
 cd $CWD
+exit $EXIT_CODE
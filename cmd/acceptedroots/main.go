@@ -1,193 +1,305 @@
 package main
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
+	"io/fs"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/rootsfetcher"
 
 	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
 )
 
-type LogInfo struct {
-	LogID         string
-	AcceptedRoots string
+// urlRewrite rewrites URLs beginning with from to begin with to instead.
+type urlRewrite struct {
+	from, to string
 }
 
-var (
-	mutex      sync.Mutex
-	wg         sync.WaitGroup
-	httpClient *http.Client
-	logInfo    map[string]*LogInfo
-)
-
-func init() {
-	httpClient = &http.Client{
-		Timeout: 30 * time.Second,
+// rewriteURL applies the rewrite with the longest prefix of u (or the first given, of equally long ones), if any.
+func rewriteURL(rewrites []urlRewrite, u string) string {
+	var best *urlRewrite
+	for i, rewrite := range rewrites {
+		if strings.HasPrefix(u, rewrite.from) && (best == nil || len(rewrite.from) > len(best.from)) {
+			best = &rewrites[i]
+		}
 	}
-
-	logInfo = make(map[string]*LogInfo)
+	if best == nil {
+		return u
+	}
+	return best.to + strings.TrimPrefix(u, best.from)
 }
 
 func main() {
+	concurrency := flag.Int("concurrency", 16, "Maximum number of logs to fetch from at once")
+	maxAttempts := flag.Int("attempts", 5, "Maximum number of attempts per log")
+	timeout := flag.Duration("timeout", 30*time.Second, "Timeout for each get-roots request")
+	initialBackoff := flag.Duration("backoff", 5*time.Second, "Delay before the first retry, doubling (with jitter) for each subsequent retry")
+	perHostInterval := flag.Duration("per-host-interval", 250*time.Millisecond, "Minimum interval between requests to the same host")
+	var urlRewrites []urlRewrite
+	flag.Func("rewrite-url", "Rewrite get-roots URLs beginning with `from=to` (e.g. to fetch from a local test server); may be repeated, in which case the longest matching prefix (or the first given, of equally long ones) applies", func(value string) error {
+		from, to, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("expected from=to")
+		}
+		urlRewrites = append(urlRewrites, urlRewrite{from: from, to: to})
+		return nil
+	})
+	flag.Usage = func() {
+		fmt.Printf("Usage: %s [flags] <Accepted Roots directory>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	outputDir := flag.Arg(0)
+
 	if err := ctloglists.LoadLogLists(); err != nil {
 		panic(err)
 	}
 
-	// Get the full list of unique base URLs of active logs.
+	targets := activeTargets()
+	var baseURLs []string
+	for baseURL := range targets {
+		baseURLs = append(baseURLs, baseURL)
+	}
+	sort.Strings(baseURLs)
+	var targetList []rootsfetcher.Target
+	for _, baseURL := range baseURLs {
+		targetList = append(targetList, targets[baseURL])
+	}
+
+	// Download the accepted roots from each log's get-roots endpoint in parallel.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fetcher := rootsfetcher.New(rootsfetcher.Config{
+		HTTPClient:      &http.Client{Timeout: *timeout},
+		Concurrency:     *concurrency,
+		MaxAttempts:     *maxAttempts,
+		InitialBackoff:  *initialBackoff,
+		PerHostInterval: *perHostInterval,
+		RewriteURL: func(getRootsURL string) string {
+			return rewriteURL(urlRewrites, getRootsURL)
+		},
+		Logf: func(format string, args ...any) { fmt.Printf(format, args...) },
+	})
+	results := fetcher.Fetch(ctx, targetList)
+
+	// Write the accepted roots of each log that responded successfully. Some logs are listed under more than one base URL, so a log has only failed if none of its base URLs responded.
+	succeeded := make(map[[sha256.Size]byte]bool)
+	var failures []rootsfetcher.Result
+	for _, result := range results {
+		if result.Err == nil {
+			fmt.Printf("Accepted roots from %s (Log ID: %x; %s): %d certificates\n", result.BaseURL, result.LogID, result.EndpointType, len(result.Certificates))
+			if result.Err = writeRootsToFile(outputDir, result); result.Err == nil {
+				succeeded[result.LogID] = true
+				continue
+			}
+			fmt.Printf("%v\n", result.Err)
+		}
+		failures = append(failures, result)
+	}
+	failures = slices.DeleteFunc(failures, func(result rootsfetcher.Result) bool {
+		return succeeded[result.LogID]
+	})
+
+	// Remove the mappings of logs that are no longer tracked, keeping the last-known-good mappings of logs that failed, then remove any orphaned roots lists.
+	if err := removeStaleFiles(outputDir, targets); err != nil {
+		fmt.Printf("Error tidying %s: %v\n", outputDir, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\nDownload complete. Retrieved accepted roots from %d logs.\n", len(succeeded))
+	if len(failures) > 0 {
+		fmt.Printf("\nFailed to retrieve accepted roots from %d log base URLs (previous accepted roots, if any, have been kept):\n", len(failures))
+		for _, result := range failures {
+			fmt.Printf("- %s (Log ID: %x): %v\n", result.BaseURL, result.LogID, result.Err)
+		}
+		os.Exit(1)
+	}
+}
+
+// activeTargets returns the unique base URLs of active logs in the bundled log lists, which must have been loaded.
+func activeTargets() map[string]rootsfetcher.Target {
+	targets := make(map[string]rootsfetcher.Target)
 	chromeActiveLogs := ctloglists.GstaticV3All.SelectByStatus([]loglist3.LogStatus{loglist3.PendingLogStatus, loglist3.QualifiedLogStatus, loglist3.UsableLogStatus})
-	loadLogBaseURLs(&chromeActiveLogs)
+	loadLogBaseURLs(targets, &chromeActiveLogs)
 
 	appleActiveLogs := ctloglists.AppleCurrent.SelectByStatus([]loglist3.LogStatus{loglist3.PendingLogStatus, loglist3.QualifiedLogStatus, loglist3.UsableLogStatus})
-	loadLogBaseURLs(&appleActiveLogs)
+	loadLogBaseURLs(targets, &appleActiveLogs)
 
-	loadLogBaseURLs(ctloglists.CrtshV3Active)
+	loadLogBaseURLs(targets, ctloglists.CrtshV3Active)
 
 	// ctloglists.MozillaV3Known doesn't include the log base URLs, and it's expected to track Chrome's log list anyway.
 
 	bimiActiveLogs := ctloglists.BimiV3Approved.SelectByStatus([]loglist3.LogStatus{loglist3.PendingLogStatus, loglist3.QualifiedLogStatus, loglist3.UsableLogStatus})
-	loadLogBaseURLs(&bimiActiveLogs)
-
-	// Download the accepted roots from each log's get-roots endpoint in parallel.
-	for li := range logInfo {
-		wg.Add(1)
-		go downloadRoots(li)
-	}
-	wg.Wait()
-
-	for logBaseURL, li := range logInfo {
-		fmt.Printf("Accepted roots from %s (Log ID: %s): %d bytes\n", logBaseURL, li.LogID, len(li.AcceptedRoots))
-		writeRootsToFile(logBaseURL, li)
-	}
-
-	fmt.Printf("\nDownload complete. Retrieved accepted roots from %d logs.\n", len(logInfo))
+	loadLogBaseURLs(targets, &bimiActiveLogs)
+	return targets
 }
 
-func loadLogBaseURLs(logList *loglist3.LogList) {
+func loadLogBaseURLs(targets map[string]rootsfetcher.Target, logList *loglist3.LogList) {
+	// Log mimics have no get-roots endpoint, so skip them.
+	logList = ctloglists.WithoutLogMimics(logList)
 	for _, operator := range logList.Operators {
 		for _, log := range operator.Logs {
 			if (log.State != nil && (log.State.Pending != nil || log.State.Qualified != nil || log.State.Usable != nil)) || !strings.HasPrefix(log.Type, "prod") {
-				logInfo[log.URL] = &LogInfo{LogID: hex.EncodeToString(log.LogID), AcceptedRoots: ""}
+				addLogBaseURL(targets, log.URL, log.LogID, ctloglists.EndpointTypeRFC6962)
 			}
 		}
 		for _, tiledLog := range operator.TiledLogs {
 			if (tiledLog.State != nil && (tiledLog.State.Pending != nil || tiledLog.State.Qualified != nil || tiledLog.State.Usable != nil)) || !strings.HasPrefix(tiledLog.Type, "prod") {
-				logInfo[tiledLog.SubmissionURL] = &LogInfo{LogID: hex.EncodeToString(tiledLog.LogID), AcceptedRoots: ""}
+				// A static-ct-api log serves get-roots beneath its submission prefix, not its monitoring prefix.
+				addLogBaseURL(targets, tiledLog.SubmissionURL, tiledLog.LogID, ctloglists.EndpointTypeStaticCTAPI)
 			}
 		}
 	}
 }
 
-func downloadRoots(baseURL string) {
-	defer wg.Done()
-
-	// Construct the get-roots URL
-	url := strings.TrimSuffix(baseURL, "/") + "/ct/v1/get-roots"
-
-	const maxAttempts = 5
-	const retryDelay = 10 * time.Second
-	var resp *http.Response
-	var body []byte
-	var err error
-
-	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		// Make the HTTP request
-		resp, err = httpClient.Get(url)
-		if err != nil {
-			fmt.Printf("Error fetching %s (attempt %d/%d): %v\n", url, attempt, maxAttempts, err)
-			goto retry
-		}
+func addLogBaseURL(targets map[string]rootsfetcher.Target, baseURL string, logID []byte, endpointType string) {
+	if baseURL == "" || len(logID) != sha256.Size {
+		return
+	}
+	// Log lists disagree about whether base URLs and submission prefixes have a trailing slash, so normalize them to avoid fetching from the same log twice.
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	targets[baseURL] = rootsfetcher.Target{BaseURL: baseURL, LogID: [sha256.Size]byte(logID), EndpointType: endpointType}
+}
 
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			fmt.Printf("Non-OK status for %s (attempt %d/%d): %d\n", url, attempt, maxAttempts, resp.StatusCode)
-			goto retry
+func writeRootsToFile(outputDir string, result rootsfetcher.Result) error {
+	// Base64 decode and parse each certificate, quarantining any that are malformed rather than silently dropping them.
+	var certificates []string
+	certDERs := make(map[string][]byte)
+	var quarantined []ctloglists.QuarantinedRoot
+	for i, b64Cert := range result.Certificates {
+		certDER, err := base64.StdEncoding.DecodeString(b64Cert)
+		if err == nil {
+			if _, err = x509.ParseCertificate(certDER); !x509.IsFatal(err) {
+				err = nil
+			}
 		}
-
-		// Read the response body
-		body, err = io.ReadAll(resp.Body)
-		resp.Body.Close()
 		if err != nil {
-			fmt.Printf("Error reading response from %s (attempt %d/%d): %v\n", url, attempt, maxAttempts, err)
-			goto retry
-		}
-
-		// Store in map (thread-safe)
-		mutex.Lock()
-		logInfo[baseURL].AcceptedRoots = string(body)
-		mutex.Unlock()
-
-		fmt.Printf("Successfully downloaded accepted roots from %s/ct/v1/get-roots\n", url)
-		return
-
-	retry:
-		if attempt < maxAttempts {
-			time.Sleep(retryDelay)
+			fmt.Printf("Quarantining certificate %d from %s: %v\n", i, result.BaseURL, err)
+			quarantined = append(quarantined, ctloglists.QuarantinedRoot{Source: "get-roots", Index: i, Raw: []byte(b64Cert), Error: err.Error()})
 			continue
 		}
-		fmt.Printf("Failed to download accepted roots from %s after %d attempts\n", url, maxAttempts)
-		return
-	}
-}
-
-func writeRootsToFile(baseURL string, li *LogInfo) {
-	// Decode the JSON response
-	var response struct {
-		Certificates []string `json:"certificates"`
-	}
-	err := json.Unmarshal([]byte(li.AcceptedRoots), &response)
-	if err != nil {
-		fmt.Printf("Error decoding JSON from %s: %v\n", baseURL, err)
-		return
+		certificates = append(certificates, b64Cert)
+		certDERs[b64Cert] = certDER
 	}
 
 	// Sort certificates alphanumerically
-	sort.Strings(response.Certificates)
+	sort.Strings(certificates)
 
-	// Base64 decode each certificate and encode as PEM
+	// Encode each certificate as PEM
 	var pemData strings.Builder
-	for i, b64Cert := range response.Certificates {
-		// Base64 decode the certificate
-		certDER, err := base64.StdEncoding.DecodeString(b64Cert)
-		if err != nil {
-			fmt.Printf("Error decoding certificate %d from %s: %v\n", i, baseURL, err)
-			continue
-		}
-
-		// Encode as PEM
+	for _, b64Cert := range certificates {
 		pemBlock := &pem.Block{
 			Type:  "CERTIFICATE",
-			Bytes: certDER,
+			Bytes: certDERs[b64Cert],
 		}
 		pem.Encode(&pemData, pemBlock)
 	}
 
 	// Write the PEM-encoded certificate list to a file. This step deduplicates lists shared by multiple logs/shards.
 	sha256PEMData := sha256.Sum256([]byte(pemData.String()))
-	filename1 := "roots_" + hex.EncodeToString(sha256PEMData[:]) + ".pem"
-	if err = os.WriteFile(filename1, []byte(pemData.String()), 0644); err != nil {
-		fmt.Printf("Error writing file %s: %v\n", filename1, err)
-		return
+	filename1 := filepath.Join(outputDir, "roots_"+hex.EncodeToString(sha256PEMData[:])+".pem")
+	if err := os.WriteFile(filename1, []byte(pemData.String()), 0644); err != nil {
+		return fmt.Errorf("error writing file %s: %v", filename1, err)
 	}
 
 	// Write the hash of the Accepted Roots list to a file.
 	// (A symlink would be tidier, but unfortunately go:embed doesn't support them).
-	filename2 := "log_" + li.LogID + ".txt"
-	if err = os.WriteFile(filename2, []byte(hex.EncodeToString(sha256PEMData[:])), 0644); err != nil {
-		fmt.Printf("Error writing file %s: %v\n", filename2, err)
-		return
+	filename2 := filepath.Join(outputDir, "log_"+hex.EncodeToString(result.LogID[:])+".txt")
+	if err := os.WriteFile(filename2, []byte(hex.EncodeToString(sha256PEMData[:])), 0644); err != nil {
+		return fmt.Errorf("error writing file %s: %v", filename2, err)
+	}
+
+	// Record the type of endpoint that the Accepted Roots were fetched from.
+	filename3 := filepath.Join(outputDir, "log_"+hex.EncodeToString(result.LogID[:])+".type")
+	if err := os.WriteFile(filename3, []byte(result.EndpointType), 0644); err != nil {
+		return fmt.Errorf("error writing file %s: %v", filename3, err)
+	}
+
+	// Record any quarantined certificates, or remove the previous record if there are none.
+	filename4 := filepath.Join(outputDir, "log_"+hex.EncodeToString(result.LogID[:])+".quarantine")
+	if len(quarantined) == 0 {
+		if err := os.Remove(filename4); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("error removing file %s: %v", filename4, err)
+		}
+	} else if data, err := json.MarshalIndent(quarantined, "", "  "); err != nil {
+		return err
+	} else if err = os.WriteFile(filename4, data, 0644); err != nil {
+		return fmt.Errorf("error writing file %s: %v", filename4, err)
+	} else {
+		fmt.Printf("Wrote %s\n", filename4)
+	}
+
+	fmt.Printf("Wrote %s, %s and %s\n", filename1, filename2, filename3)
+	return nil
+}
+
+// removeStaleFiles removes the log_<id>.* files of logs that are no longer tracked, and then the roots_<hash>.pem files that no remaining log_<id>.txt file refers to.
+func removeStaleFiles(outputDir string, targets map[string]rootsfetcher.Target) error {
+	trackedLogIDs := make(map[string]bool)
+	for _, target := range targets {
+		trackedLogIDs[hex.EncodeToString(target.LogID[:])] = true
+	}
+
+	dirEntry, err := os.ReadDir(outputDir)
+	if err != nil {
+		return err
+	}
+
+	referencedRootsLists := make(map[string]bool)
+	for _, file := range dirEntry {
+		if !strings.HasPrefix(file.Name(), "log_") {
+			continue
+		}
+		filename := filepath.Join(outputDir, file.Name())
+		if logID, _, _ := strings.Cut(strings.TrimPrefix(file.Name(), "log_"), "."); !trackedLogIDs[logID] {
+			fmt.Printf("Removing %s (log no longer tracked)\n", filename)
+			if err = os.Remove(filename); err != nil {
+				return err
+			}
+			continue
+		} else if !strings.HasSuffix(file.Name(), ".txt") {
+			continue
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		referencedRootsLists[strings.TrimSpace(string(data))] = true
+	}
+
+	for _, file := range dirEntry {
+		if !strings.HasPrefix(file.Name(), "roots_") || !strings.HasSuffix(file.Name(), ".pem") {
+			continue
+		}
+		if !referencedRootsLists[strings.TrimSuffix(strings.TrimPrefix(file.Name(), "roots_"), ".pem")] {
+			filename := filepath.Join(outputDir, file.Name())
+			fmt.Printf("Removing %s (no longer referenced)\n", filename)
+			if err = os.Remove(filename); err != nil {
+				return err
+			}
+		}
 	}
 
-	fmt.Printf("Wrote %s and %s\n", filename1, filename2)
+	return nil
 }
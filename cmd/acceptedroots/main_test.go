@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/rootsfetcher"
+)
+
+// TestWriteRootsToFileReproducesBundledFiles checks that rewriting each bundled log's Accepted Roots, as returned by get-roots, reproduces the bundled files exactly, so that the collector doesn't churn them.
+func TestWriteRootsToFileReproducesBundledFiles(t *testing.T) {
+	if err := ctloglists.LoadLogLists(); err != nil {
+		t.Fatal(err)
+	}
+	bundledDir := filepath.Join("..", "..", "files", "acceptedroots")
+	outputDir := t.TempDir()
+	written := 0
+	for _, target := range activeTargets() {
+		hexLogID := hex.EncodeToString(target.LogID[:])
+		rootsListHash, err := os.ReadFile(filepath.Join(bundledDir, "log_"+hexLogID+".txt"))
+		if os.IsNotExist(err) {
+			// The collector hasn't yet fetched this log's Accepted Roots.
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		pemData, err := os.ReadFile(filepath.Join(bundledDir, "roots_"+string(rootsListHash)+".pem"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := rootsfetcher.Result{Target: target}
+		for block, rest := pem.Decode(pemData); block != nil; block, rest = pem.Decode(rest) {
+			result.Certificates = append(result.Certificates, base64.StdEncoding.EncodeToString(block.Bytes))
+		}
+		if err = writeRootsToFile(outputDir, result); err != nil {
+			t.Fatal(err)
+		}
+		written++
+	}
+	if written == 0 {
+		t.Fatal("no bundled Accepted Roots")
+	}
+
+	files, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		got, err := os.ReadFile(filepath.Join(outputDir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, err := os.ReadFile(filepath.Join(bundledDir, file.Name())); err != nil {
+			t.Errorf("%s: %v", file.Name(), err)
+		} else if !bytes.Equal(got, want) {
+			t.Errorf("%s: rewritten file differs from the bundled one", file.Name())
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/internal/loglistsource"
+)
+
+const acceptedRootsDir = "files/acceptedroots"
+
+// snapshot maps hex-encoded log IDs to the hex-encoded fingerprints of the roots they accept.
+type snapshot map[string]map[string]struct{}
+
+type pairKey struct {
+	logID       string
+	fingerprint string
+}
+
+type closedPeriod struct {
+	pairKey
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+type revision struct {
+	hash string
+	time time.Time
+}
+
+func main() {
+	full := flag.Bool("full", false, "Rebuild the history from the start of this repository's git history, rather than updating it incrementally")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--full] <history file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Derives the Accepted Roots history from the git history of %s, updating the specified history file.\n"+
+			"The history is rebuilt from scratch if the revision it was last derived from is not an ancestor of HEAD.\n", acceptedRootsDir)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(flag.Arg(0), *full); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(historyFile string, full bool) error {
+	var history ctloglists.AcceptedRootsHistory
+	if !full {
+		if data, err := os.ReadFile(historyFile); errors.Is(err, fs.ErrNotExist) {
+			full = true
+		} else if err != nil {
+			return err
+		} else if err = json.Unmarshal(data, &history); err != nil {
+			return fmt.Errorf("%s: %v", historyFile, err)
+		} else if history.Revision != "" && !isAncestor(history.Revision) {
+			// e.g. the history was derived from a commit that was never pushed, or that has since been rewritten.
+			fmt.Printf("History revision %s is not an ancestor of HEAD; rebuilding the history.\n", history.Revision)
+			full = true
+		}
+	}
+	if full {
+		history = ctloglists.AcceptedRootsHistory{}
+	}
+
+	// Reconstruct the open and closed periods from the existing history.
+	open := make(map[pairKey]time.Time)
+	var closed []closedPeriod
+	for _, period := range history.Periods {
+		if period.Log < 0 || period.Log >= len(history.Logs) {
+			return fmt.Errorf("%s: invalid log index %d", historyFile, period.Log)
+		}
+		for _, root := range period.Roots {
+			if root < 0 || root >= len(history.Roots) {
+				return fmt.Errorf("%s: invalid root index %d", historyFile, root)
+			}
+			key := pairKey{logID: history.Logs[period.Log], fingerprint: history.Roots[root]}
+			if period.LastSeen == nil {
+				open[key] = period.FirstSeen
+			} else {
+				closed = append(closed, closedPeriod{pairKey: key, firstSeen: period.FirstSeen, lastSeen: *period.LastSeen})
+			}
+		}
+	}
+
+	revisions, err := listRevisions(history.Revision)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		fmt.Printf("History is up to date as of %s.\n", history.Revision)
+		return nil
+	}
+
+	reader, err := newGitReader()
+	if err != nil {
+		return err
+	}
+	defer reader.close()
+
+	previousTime := history.RevisionTime
+	for _, rev := range revisions {
+		snap, err := reader.readSnapshot(rev.hash)
+		if err != nil {
+			return fmt.Errorf("%s: %v", rev.hash, err)
+		}
+
+		// Close the periods of roots that are no longer accepted, as of the previous snapshot.
+		for key, firstSeen := range open {
+			if _, ok := snap[key.logID][key.fingerprint]; !ok {
+				closed = append(closed, closedPeriod{pairKey: key, firstSeen: firstSeen, lastSeen: previousTime})
+				delete(open, key)
+			}
+		}
+		// Open periods for roots that are newly accepted.
+		for logID, fingerprints := range snap {
+			for fingerprint := range fingerprints {
+				key := pairKey{logID: logID, fingerprint: fingerprint}
+				if _, ok := open[key]; !ok {
+					open[key] = rev.time
+				}
+			}
+		}
+		previousTime = rev.time
+	}
+
+	history.Revision = revisions[len(revisions)-1].hash
+	history.RevisionTime = revisions[len(revisions)-1].time
+	buildPeriods(&history, open, closed)
+
+	if err = writeHistory(historyFile, &history); err != nil {
+		return err
+	}
+	fmt.Printf("Processed %d revision(s); history is up to date as of %s.\n", len(revisions), history.Revision)
+	return nil
+}
+
+// listRevisions returns the first-parent commits after since (or all, if since is empty) that changed the Accepted Roots, oldest first.
+// since must be an ancestor of HEAD.
+func listRevisions(since string) ([]revision, error) {
+	revRange := "HEAD"
+	if since != "" {
+		revRange = since + "..HEAD"
+	}
+
+	out, err := loglistsource.Git("log", "--reverse", "--first-parent", "--format=%H %cI", revRange, "--", acceptedRootsDir)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []revision
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		hash, timestamp, _ := strings.Cut(line, " ")
+		t, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision{hash: hash, time: t.UTC()})
+	}
+	return revisions, nil
+}
+
+// isAncestor returns true if rev is a commit that is an ancestor of (or is) HEAD.
+func isAncestor(rev string) bool {
+	_, err := loglistsource.Git("merge-base", "--is-ancestor", rev, "HEAD")
+	return err == nil
+}
+
+// gitReader reads blobs via a long-running "git cat-file --batch" process, caching the fingerprints found in each roots list.
+type gitReader struct {
+	cmd         *exec.Cmd
+	stdin       io.WriteCloser
+	stdout      *bufio.Reader
+	rootsByBlob map[string][]string
+}
+
+func newGitReader() (*gitReader, error) {
+	root, err := loglistsource.RepositoryRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "cat-file", "--batch")
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &gitReader{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout), rootsByBlob: make(map[string][]string)}, nil
+}
+
+func (r *gitReader) close() {
+	r.stdin.Close()
+	r.cmd.Wait()
+}
+
+func (r *gitReader) readBlob(object string) ([]byte, error) {
+	if _, err := fmt.Fprintln(r.stdin, object); err != nil {
+		return nil, err
+	}
+	header, err := r.stdout.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 || fields[1] != "blob" {
+		return nil, fmt.Errorf("unexpected git cat-file output for %s: %q", object, strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size+1) // Includes the trailing newline.
+	if _, err = io.ReadFull(r.stdout, data); err != nil {
+		return nil, err
+	}
+	return data[:size], nil
+}
+
+// readSnapshot reads each log's Accepted Roots as of the specified revision.
+func (r *gitReader) readSnapshot(rev string) (snapshot, error) {
+	out, err := loglistsource.Git("ls-tree", rev, acceptedRootsDir+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	rootsListBlobs := make(map[string]string)
+	logBlobs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		info, filename, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		filename = path.Base(filename)
+		if hash, ok := strings.CutPrefix(filename, "roots_"); ok && strings.HasSuffix(hash, ".pem") {
+			rootsListBlobs[strings.TrimSuffix(hash, ".pem")] = fields[2]
+		} else if logID, ok := strings.CutPrefix(filename, "log_"); ok && strings.HasSuffix(logID, ".txt") {
+			logBlobs[strings.TrimSuffix(logID, ".txt")] = fields[2]
+		}
+	}
+
+	snap := make(snapshot)
+	for logID, blob := range logBlobs {
+		data, err := r.readBlob(blob)
+		if err != nil {
+			return nil, err
+		}
+		rootsListHash := strings.TrimSpace(string(data))
+		rootsBlob, ok := rootsListBlobs[rootsListHash]
+		if !ok {
+			return nil, fmt.Errorf("log %s refers to missing roots list %s", logID, rootsListHash)
+		}
+		fingerprints, ok := r.rootsByBlob[rootsBlob]
+		if !ok {
+			if data, err = r.readBlob(rootsBlob); err != nil {
+				return nil, err
+			}
+			fingerprints = pemFingerprints(data)
+			r.rootsByBlob[rootsBlob] = fingerprints
+		}
+		snap[logID] = make(map[string]struct{}, len(fingerprints))
+		for _, fingerprint := range fingerprints {
+			snap[logID][fingerprint] = struct{}{}
+		}
+	}
+	return snap, nil
+}
+
+// pemFingerprints returns the hex-encoded SHA-256 fingerprints of the certificates in data, without parsing them.
+func pemFingerprints(data []byte) []string {
+	var fingerprints []string
+	for len(data) > 0 {
+		var block *pem.Block
+		if block, data = pem.Decode(data); block == nil {
+			break
+		} else if block.Type != "CERTIFICATE" {
+			continue
+		}
+		fingerprint := sha256.Sum256(block.Bytes)
+		fingerprints = append(fingerprints, hex.EncodeToString(fingerprint[:]))
+	}
+	return fingerprints
+}
+
+// buildPeriods replaces history's periods, appending any new logs and roots to its index tables so that existing indexes remain stable.
+func buildPeriods(history *ctloglists.AcceptedRootsHistory, open map[pairKey]time.Time, closed []closedPeriod) {
+	logIndex := make(map[string]int)
+	for i, logID := range history.Logs {
+		logIndex[logID] = i
+	}
+	rootIndex := make(map[string]int)
+	for i, fingerprint := range history.Roots {
+		rootIndex[fingerprint] = i
+	}
+
+	all := closed
+	for key, firstSeen := range open {
+		all = append(all, closedPeriod{pairKey: key, firstSeen: firstSeen})
+	}
+
+	var newLogs, newRoots []string
+	for _, p := range all {
+		if _, ok := logIndex[p.logID]; !ok {
+			logIndex[p.logID] = -1
+			newLogs = append(newLogs, p.logID)
+		}
+		if _, ok := rootIndex[p.fingerprint]; !ok {
+			rootIndex[p.fingerprint] = -1
+			newRoots = append(newRoots, p.fingerprint)
+		}
+	}
+	sort.Strings(newLogs)
+	for _, logID := range newLogs {
+		logIndex[logID] = len(history.Logs)
+		history.Logs = append(history.Logs, logID)
+	}
+	sort.Strings(newRoots)
+	for _, fingerprint := range newRoots {
+		rootIndex[fingerprint] = len(history.Roots)
+		history.Roots = append(history.Roots, fingerprint)
+	}
+
+	// Group the roots that share a log and period.
+	type groupKey struct {
+		log       int
+		firstSeen time.Time
+		lastSeen  time.Time
+	}
+	groups := make(map[groupKey][]int)
+	for _, p := range all {
+		key := groupKey{log: logIndex[p.logID], firstSeen: p.firstSeen, lastSeen: p.lastSeen}
+		groups[key] = append(groups[key], rootIndex[p.fingerprint])
+	}
+
+	history.Periods = make([]ctloglists.AcceptedRootsHistoryPeriod, 0, len(groups))
+	for key, roots := range groups {
+		sort.Ints(roots)
+		period := ctloglists.AcceptedRootsHistoryPeriod{Log: key.log, FirstSeen: key.firstSeen, Roots: roots}
+		if !key.lastSeen.IsZero() {
+			lastSeen := key.lastSeen
+			period.LastSeen = &lastSeen
+		}
+		history.Periods = append(history.Periods, period)
+	}
+	sort.Slice(history.Periods, func(i, j int) bool {
+		pi, pj := history.Periods[i], history.Periods[j]
+		if pi.Log != pj.Log {
+			return pi.Log < pj.Log
+		} else if !pi.FirstSeen.Equal(pj.FirstSeen) {
+			return pi.FirstSeen.Before(pj.FirstSeen)
+		} else if (pi.LastSeen == nil) != (pj.LastSeen == nil) {
+			return pj.LastSeen == nil
+		} else if pi.LastSeen != nil {
+			return pi.LastSeen.Before(*pj.LastSeen)
+		}
+		return false
+	})
+}
+
+// writeHistory writes the history with one log, root or period per line, so that changes produce small diffs.
+func writeHistory(historyFile string, history *ctloglists.AcceptedRootsHistory) error {
+	var buf bytes.Buffer
+	writeJSON := func(v any) {
+		data, _ := json.Marshal(v)
+		buf.Write(data)
+	}
+	writeList := func(name string, n int, item func(int) any) {
+		fmt.Fprintf(&buf, "  %q: [", name)
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n    ")
+			writeJSON(item(i))
+		}
+		if n > 0 {
+			buf.WriteString("\n  ")
+		}
+		buf.WriteString("]")
+	}
+
+	buf.WriteString("{\n  \"revision\": ")
+	writeJSON(history.Revision)
+	buf.WriteString(",\n  \"revision_time\": ")
+	writeJSON(history.RevisionTime)
+	buf.WriteString(",\n")
+	writeList("logs", len(history.Logs), func(i int) any { return history.Logs[i] })
+	buf.WriteString(",\n")
+	writeList("roots", len(history.Roots), func(i int) any { return history.Roots[i] })
+	buf.WriteString(",\n")
+	writeList("periods", len(history.Periods), func(i int) any { return history.Periods[i] })
+	buf.WriteString("\n}\n")
+
+	if err := os.MkdirAll(path.Dir(historyFile), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(historyFile, buf.Bytes(), 0644)
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/internal/loglistsource"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+const acceptedRootsDir = "files/acceptedroots"
+
+// feedID and the tag URIs of the entries identify the feed and its entries independently of where the feed is published, so that feed readers don't duplicate entries when the feed is regenerated.
+const feedID = "tag:crt.sh,2024:ctloglists"
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// commit is a first-parent commit of this repository that changed a bundled log list or the Accepted Roots.
+type commit struct {
+	hash  string
+	time  time.Time
+	paths []string // The changed paths.
+}
+
+func main() {
+	maxEntries := flag.Int("max-entries", 100, "Maximum number of entries, most recent first")
+	since := flag.String("since", "", "Only describe changes made after this git revision")
+	repositoryURL := flag.String("repository-url", "https://github.com/crtsh/ctloglists", "URL of this repository, for linking to the commits that made each change")
+	selfURL := flag.String("self-url", "", "URL at which the feed will be published")
+	output := flag.String("output", "", "File to write the feed to, instead of standard output")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Walks this repository's git history, writing an Atom feed with an entry for each log added to, removed from or changing state in a bundled log list, and each root added to or removed from logs' Accepted Roots.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 || *maxEntries < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	feed, err := buildFeed(*since, *maxEntries, strings.TrimSuffix(*repositoryURL, "/"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	feed.Links = append(feed.Links, atomLink{Rel: "alternate", Href: *repositoryURL})
+	if *selfURL != "" {
+		feed.Links = append(feed.Links, atomLink{Rel: "self", Href: *selfURL})
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	data = append([]byte(xml.Header), append(data, '\n')...)
+	if *output == "" {
+		os.Stdout.Write(data)
+	} else if err = os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func buildFeed(since string, maxEntries int, repositoryURL string) (*atomFeed, error) {
+	var paths []string
+	for _, name := range ctloglists.LogListNames {
+		paths = append(paths, ctloglists.LogListFilename(name))
+	}
+	paths = append(paths, acceptedRootsDir)
+	commits, err := listCommits(since, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &atomFeed{ID: feedID, Title: "CT log list and Accepted Roots changes", Author: atomAuthor{Name: "ctloglists"}}
+	reader := snapshotReader{logLists: make(map[logListKey]*loglist3.LogList), acceptedRoots: make(map[string]acceptedRootsSnapshot)}
+	for _, c := range commits {
+		if len(feed.Entries) >= maxEntries {
+			break
+		}
+		entries, err := describeCommit(&reader, c)
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			entries[i].Updated = c.time.Format(time.RFC3339)
+			entries[i].Link = atomLink{Href: repositoryURL + "/commit/" + c.hash}
+		}
+		feed.Entries = append(feed.Entries, entries...)
+	}
+	if len(feed.Entries) > maxEntries {
+		feed.Entries = feed.Entries[:maxEntries]
+	}
+
+	if len(feed.Entries) > 0 {
+		feed.Updated = feed.Entries[0].Updated
+	} else if len(commits) > 0 {
+		feed.Updated = commits[0].time.Format(time.RFC3339)
+	} else {
+		feed.Updated = time.Unix(0, 0).UTC().Format(time.RFC3339)
+	}
+	return feed, nil
+}
+
+// listCommits returns the first-parent commits after since (or all, if since is empty) that changed any of paths, most recent first.
+func listCommits(since string, paths []string) ([]commit, error) {
+	revRange := "HEAD"
+	if since != "" {
+		revRange = since + "..HEAD"
+	}
+	out, err := loglistsource.Git(append([]string{"log", "--first-parent", "--format=%x00%H %cI", "--name-only", revRange, "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []commit
+	for _, record := range strings.Split(string(out), "\x00") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		if lines[0] == "" {
+			continue
+		}
+		hash, timestamp, _ := strings.Cut(lines[0], " ")
+		t, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		c := commit{hash: hash, time: t.UTC()}
+		for _, path := range lines[1:] {
+			if path = strings.TrimSpace(path); path != "" {
+				c.paths = append(c.paths, path)
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// snapshotReader reads log lists and the Accepted Roots as of git revisions, caching them because consecutive commits each read the same snapshots: log lists by commit hash and name, and the Accepted Roots by tree hash.
+type snapshotReader struct {
+	logLists      map[logListKey]*loglist3.LogList
+	acceptedRoots map[string]acceptedRootsSnapshot
+}
+
+type logListKey struct {
+	hash, name string
+}
+
+// acceptedRootsSnapshot holds the Accepted Roots as of a git revision, and the certificates quarantined from them.
+type acceptedRootsSnapshot struct {
+	roots       ctloglists.AcceptedRootsSet
+	quarantined map[[sha256.Size]byte][]ctloglists.QuarantinedRoot
+}
+
+// loadLogList loads the named bundled log list as of the commit with the given hash.
+func (r *snapshotReader) loadLogList(hash, name string) (*loglist3.LogList, error) {
+	key := logListKey{hash: hash, name: name}
+	if logList, ok := r.logLists[key]; ok {
+		return logList, nil
+	}
+	logList, err := loglistsource.LoadAtRevision(hash, name)
+	if err != nil {
+		return nil, err
+	}
+	r.logLists[key] = logList
+	return logList, nil
+}
+
+// readAcceptedRoots reads the Accepted Roots, and the certificates quarantined from them, as of git revision rev. If they didn't exist as of rev, no logs have any Accepted Roots.
+func (r *snapshotReader) readAcceptedRoots(rev string) (acceptedRootsSnapshot, error) {
+	// If the tree hash can't be found, leave it to ExtractDirectoryAtRevision to tell whether the Accepted Roots didn't exist or rev is invalid.
+	tree, treeErr := loglistsource.Git("rev-parse", "--verify", "--quiet", rev+":"+acceptedRootsDir)
+	if treeErr == nil {
+		if snapshot, ok := r.acceptedRoots[string(tree)]; ok {
+			return snapshot, nil
+		}
+	}
+
+	dir, err := loglistsource.ExtractDirectoryAtRevision(rev, acceptedRootsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return acceptedRootsSnapshot{roots: ctloglists.AcceptedRootsSet{}}, nil
+	} else if err != nil {
+		return acceptedRootsSnapshot{}, err
+	}
+	defer os.RemoveAll(dir)
+	var snapshot acceptedRootsSnapshot
+	if snapshot.roots, snapshot.quarantined, err = ctloglists.ReadAcceptedRoots(os.DirFS(filepath.Join(dir, acceptedRootsDir))); err != nil {
+		return acceptedRootsSnapshot{}, fmt.Errorf("%s:%s: %v", rev, acceptedRootsDir, err)
+	}
+	if treeErr == nil {
+		r.acceptedRoots[string(tree)] = snapshot
+	}
+	return snapshot, nil
+}
+
+// describeCommit returns an entry for each semantic change made by c, compared with its first parent.
+// The first commit only establishes what's being tracked, so it has no entries.
+func describeCommit(reader *snapshotReader, c commit) ([]atomEntry, error) {
+	parent := c.hash + "^"
+	parentHash, err := loglistsource.ResolveRevision(parent)
+	if err != nil {
+		return nil, nil
+	}
+	idPrefix := fmt.Sprintf("tag:crt.sh,%s:ctloglists/%s/", c.time.Format("2006-01-02"), c.hash)
+
+	var entries []atomEntry
+	acceptedRootsChanged := false
+	for _, name := range ctloglists.LogListNames {
+		filename := ctloglists.LogListFilename(name)
+		if !slices.Contains(c.paths, filename) {
+			continue
+		}
+		oldList, err := reader.loadLogList(parentHash, name)
+		if err != nil {
+			return nil, err
+		}
+		newList, err := reader.loadLogList(c.hash, name)
+		if err != nil {
+			return nil, err
+		}
+
+		changes := ctloglists.ClassifyChanges(oldList, newList)
+		for _, dl := range changes.Added {
+			entries = append(entries, logEntry(idPrefix+"added/"+name+"/", dl.LogID, fmt.Sprintf("%s added to %s", logName(dl), name), dl))
+		}
+		for _, dl := range changes.Removed {
+			entries = append(entries, logEntry(idPrefix+"removed/"+name+"/", dl.LogID, fmt.Sprintf("%s removed from %s", logName(dl), name), dl))
+		}
+		for _, change := range changes.KeyChanges {
+			entry := logEntry(idPrefix+"key_change/"+name+"/", change.New.LogID, fmt.Sprintf("%s has a new key in %s", logName(change.New), name), change.New)
+			entry.Content.Text += fmt.Sprintf("\nPrevious log ID: %s", base64.StdEncoding.EncodeToString(change.Old.LogID))
+			entries = append(entries, entry)
+		}
+		for _, change := range changes.StateChanges {
+			entry := logEntry(idPrefix+"state/"+name+"/", change.LogID, fmt.Sprintf("%s became %s in %s", logName(change.DiffLog), change.To, name), change.DiffLog)
+			entry.Content.Text += fmt.Sprintf("\nPrevious state: %s", change.From)
+			entries = append(entries, entry)
+		}
+		if slices.Contains(changes.Kinds, ctloglists.ChangeOther) {
+			entries = append(entries, atomEntry{
+				ID:      idPrefix + "other/" + name,
+				Title:   fmt.Sprintf("Other changes to logs or operators in %s", name),
+				Content: atomContent{Type: "text", Text: fmt.Sprintf("See diffloglists %s@%s %s@%s.", name, parent, name, c.hash)},
+			})
+		}
+	}
+	for _, path := range c.paths {
+		acceptedRootsChanged = acceptedRootsChanged || strings.HasPrefix(path, acceptedRootsDir+"/")
+	}
+	if !acceptedRootsChanged {
+		return entries, nil
+	}
+
+	oldRoots, err := reader.readAcceptedRoots(parentHash)
+	if err != nil {
+		return nil, err
+	}
+	newRoots, err := reader.readAcceptedRoots(c.hash)
+	if err != nil {
+		return nil, err
+	}
+	// Name logs as they appear in the log lists as of c, or else as they appeared before c.
+	var logLists []*loglist3.LogList
+	for _, hash := range []string{c.hash, parentHash} {
+		for _, name := range ctloglists.LogListNames {
+			logList, err := reader.loadLogList(hash, name)
+			if err != nil {
+				return nil, err
+			}
+			logLists = append(logLists, logList)
+		}
+	}
+	return append(entries, rootEntries(idPrefix, ctloglists.DiffAcceptedRoots(oldRoots.roots, newRoots.roots, oldRoots.quarantined, newRoots.quarantined), ctloglists.SummarizeLogs(logLists...))...), nil
+}
+
+// rootEntries returns an entry for each root added to or removed from the Accepted Roots of one or more logs, ordered by subject, followed by an entry for each log from whose Accepted Roots certificates were newly quarantined.
+func rootEntries(idPrefix string, changes []ctloglists.AcceptedRootsChange, summaries map[[sha256.Size]byte]ctloglists.LogSummary) []atomEntry {
+	type rootChange struct {
+		direction string
+		root      ctloglists.AcceptedRoot
+		logs      []string
+	}
+	var rootChanges []*rootChange
+	byKey := make(map[string]*rootChange)
+	logDescription := func(logID [sha256.Size]byte) string {
+		if summary, ok := summaries[logID]; ok {
+			return fmt.Sprintf("%s (%s)", summary.Description, summary.Operator)
+		}
+		return "Unknown log " + hex.EncodeToString(logID[:])
+	}
+	add := func(direction string, root ctloglists.AcceptedRoot, logID [sha256.Size]byte) {
+		key := direction + string(root.Fingerprint[:])
+		rc, ok := byKey[key]
+		if !ok {
+			rc = &rootChange{direction: direction, root: root}
+			byKey[key] = rc
+			rootChanges = append(rootChanges, rc)
+		}
+		rc.logs = append(rc.logs, logDescription(logID))
+	}
+	for _, change := range changes {
+		for _, root := range change.Added {
+			add("added", root, change.LogID)
+		}
+		for _, root := range change.Removed {
+			add("removed", root, change.LogID)
+		}
+	}
+	slices.SortFunc(rootChanges, func(a, b *rootChange) int {
+		if c := strings.Compare(a.root.Subject, b.root.Subject); c != 0 {
+			return c
+		}
+		return strings.Compare(a.direction, b.direction)
+	})
+
+	var entries []atomEntry
+	for _, rc := range rootChanges {
+		slices.Sort(rc.logs)
+		fingerprint := hex.EncodeToString(rc.root.Fingerprint[:])
+		preposition := "to"
+		if rc.direction == "removed" {
+			preposition = "from"
+		}
+		title := fmt.Sprintf("%s %s %s the Accepted Roots of %d logs", rc.root.Subject, rc.direction, preposition, len(rc.logs))
+		if len(rc.logs) == 1 {
+			title = fmt.Sprintf("%s %s %s the Accepted Roots of %s", rc.root.Subject, rc.direction, preposition, rc.logs[0])
+		}
+		entries = append(entries, atomEntry{
+			ID:      idPrefix + "roots_" + rc.direction + "/" + fingerprint,
+			Title:   title,
+			Content: atomContent{Type: "text", Text: fmt.Sprintf("Root: %s\nSHA-256 fingerprint: %s\nLogs:\n- %s", rc.root.Subject, fingerprint, strings.Join(rc.logs, "\n- "))},
+		})
+	}
+
+	for _, change := range changes {
+		if len(change.Quarantined) == 0 {
+			continue
+		}
+		var failures []string
+		for _, qr := range change.Quarantined {
+			failures = append(failures, fmt.Sprintf("%s #%d: %s", qr.Source, qr.Index, qr.Error))
+		}
+		entries = append(entries, atomEntry{
+			ID:      idPrefix + "roots_quarantined/" + hex.EncodeToString(change.LogID[:]),
+			Title:   fmt.Sprintf("%d certificates quarantined from the Accepted Roots of %s", len(change.Quarantined), logDescription(change.LogID)),
+			Content: atomContent{Type: "text", Text: fmt.Sprintf("Certificates that couldn't be parsed, and so are excluded from the log's Accepted Roots:\n- %s", strings.Join(failures, "\n- "))},
+		})
+	}
+	return entries
+}
+
+func logEntry(idPrefix string, logID []byte, title string, dl ctloglists.DiffLog) atomEntry {
+	return atomEntry{
+		ID:      idPrefix + hex.EncodeToString(logID),
+		Title:   title,
+		Content: atomContent{Type: "text", Text: fmt.Sprintf("Log: %s\nOperator: %s\nURL: %s\nLog ID: %s", dl.Description, dl.Operator, dl.URL, base64.StdEncoding.EncodeToString(dl.LogID))},
+	}
+}
+
+func logName(dl ctloglists.DiffLog) string {
+	return fmt.Sprintf("%s (%s)", dl.Description, dl.Operator)
+}
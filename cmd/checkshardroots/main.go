@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/crtsh/ctloglists"
+)
+
+type rootJSON struct {
+	Fingerprint string   `json:"sha256_fingerprint"`
+	Subject     string   `json:"subject"`
+	MissingFrom []string `json:"missing_from"`
+}
+
+type shardJSON struct {
+	LogID         string `json:"log_id"`
+	Description   string `json:"description,omitempty"`
+	RootsListHash string `json:"roots_list_hash,omitempty"`
+	Roots         int    `json:"roots"`
+}
+
+type familyJSON struct {
+	Operator   string      `json:"operator"`
+	Family     string      `json:"family"`
+	Consistent bool        `json:"consistent"`
+	Shards     []shardJSON `json:"shards"`
+	Missing    []rootJSON  `json:"missing_roots"`
+}
+
+func main() {
+	listName := flag.String("list", "gstatic-all", "Log list to group shards from: "+strings.Join(ctloglists.LogListNames, ", "))
+	format := flag.String("format", "text", "Output format: text or json")
+	all := flag.Bool("all", false, "Report every shard family, not just those with inconsistent Accepted Roots")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--list <name>] [--format text|json] [--all]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Reports roots accepted by some, but not all, of the temporal shards in each family of logs.\n")
+		fmt.Fprintf(os.Stderr, "Exits with status 2 if any family's shards have inconsistent Accepted Roots.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 || (*format != "text" && *format != "json") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := ctloglists.LoadLogLists(); err != nil {
+		panic(err)
+	} else if err = ctloglists.LoadAcceptedRoots(); err != nil {
+		panic(err)
+	}
+	logList := ctloglists.LogListByName(*listName)
+	if logList == nil {
+		fmt.Fprintf(os.Stderr, "Error: unknown log list %q\n", *listName)
+		os.Exit(1)
+	}
+
+	var reports []ctloglists.ShardRootsReport
+	inconsistent := false
+	for _, family := range ctloglists.ShardFamilies(logList) {
+		report := ctloglists.CompareShardRoots(family)
+		if !report.Consistent {
+			inconsistent = true
+		}
+		if *all || !report.Consistent {
+			reports = append(reports, report)
+		}
+	}
+
+	switch *format {
+	case "json":
+		printJSON(reports)
+	default:
+		printText(reports)
+	}
+	if inconsistent {
+		os.Exit(2)
+	}
+}
+
+func printText(reports []ctloglists.ShardRootsReport) {
+	if len(reports) == 0 {
+		fmt.Printf("All shard families have consistent Accepted Roots.\n")
+		return
+	}
+	for i, report := range reports {
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		status := "consistent"
+		if !report.Consistent {
+			status = "INCONSISTENT"
+		}
+		fmt.Printf("%s '%s' (%d shards): %s\n", report.Family.Operator, report.Family.Name, len(report.Shards), status)
+		descriptions := make(map[[32]byte]string)
+		for _, shard := range report.Shards {
+			descriptions[shard.LogID] = shard.Description
+			if shard.RootsListHash == nil {
+				fmt.Printf("  %s (%s): Accepted Roots unknown\n", shard.Description, hex.EncodeToString(shard.LogID[:]))
+			} else {
+				fmt.Printf("  %s (%s): %d roots [%s]\n", shard.Description, hex.EncodeToString(shard.LogID[:]), shard.Roots, hex.EncodeToString(shard.RootsListHash[:]))
+			}
+		}
+		for _, missing := range report.Missing {
+			fmt.Printf("  ! %s %s\n", hex.EncodeToString(missing.Root.Fingerprint[:]), missing.Root.Subject)
+			for _, logID := range missing.MissingFrom {
+				fmt.Printf("      missing from %s\n", descriptions[logID])
+			}
+		}
+	}
+}
+
+func printJSON(reports []ctloglists.ShardRootsReport) {
+	output := []familyJSON{}
+	for _, report := range reports {
+		fj := familyJSON{Operator: report.Family.Operator, Family: report.Family.Name, Consistent: report.Consistent, Shards: []shardJSON{}, Missing: []rootJSON{}}
+		for _, shard := range report.Shards {
+			sj := shardJSON{LogID: hex.EncodeToString(shard.LogID[:]), Description: shard.Description, Roots: shard.Roots}
+			if shard.RootsListHash != nil {
+				sj.RootsListHash = hex.EncodeToString(shard.RootsListHash[:])
+			}
+			fj.Shards = append(fj.Shards, sj)
+		}
+		for _, missing := range report.Missing {
+			rj := rootJSON{Fingerprint: hex.EncodeToString(missing.Root.Fingerprint[:]), Subject: missing.Root.Subject}
+			for _, logID := range missing.MissingFrom {
+				rj.MissingFrom = append(rj.MissingFrom, hex.EncodeToString(logID[:]))
+			}
+			fj.Missing = append(fj.Missing, rj)
+		}
+		output = append(output, fj)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(output)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/internal/loglistsource"
+)
+
+const acceptedRootsDir = "files/acceptedroots"
+
+type rootJSON struct {
+	Fingerprint string    `json:"sha256_fingerprint"`
+	Subject     string    `json:"subject"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+type changeJSON struct {
+	LogID       string                       `json:"log_id"`
+	Description string                       `json:"description,omitempty"`
+	Operator    string                       `json:"operator,omitempty"`
+	URL         string                       `json:"url,omitempty"`
+	Added       []rootJSON                   `json:"added"`
+	Removed     []rootJSON                   `json:"removed"`
+	Quarantined []ctloglists.QuarantinedRoot `json:"quarantined,omitempty"`
+}
+
+func main() {
+	format := flag.String("format", "text", "Output format: text or json")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--format text|json] <old> <new>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Each of <old> and <new> is an Accepted Roots directory, or a git revision of this repository.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 || (*format != "text" && *format != "json") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := ctloglists.LoadLogLists(); err != nil {
+		panic(err)
+	}
+
+	oldRoots, oldQuarantined, err := readAcceptedRoots(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	newRoots, newQuarantined, err := readAcceptedRoots(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	changes := ctloglists.DiffAcceptedRoots(oldRoots, newRoots, oldQuarantined, newQuarantined)
+	switch *format {
+	case "json":
+		printJSON(changes)
+	default:
+		printText(changes)
+	}
+}
+
+// readAcceptedRoots reads the Accepted Roots, and the certificates quarantined from them, from arg, which is either a directory or a git revision.
+func readAcceptedRoots(arg string) (ctloglists.AcceptedRootsSet, map[[sha256.Size]byte][]ctloglists.QuarantinedRoot, error) {
+	if fi, err := os.Stat(arg); err == nil && fi.IsDir() {
+		return ctloglists.ReadAcceptedRoots(os.DirFS(arg))
+	}
+
+	dir, err := loglistsource.ExtractDirectoryAtRevision(arg, acceptedRootsDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%q is not a directory or a git revision: %v", arg, err)
+	}
+	defer os.RemoveAll(dir)
+	return ctloglists.ReadAcceptedRoots(os.DirFS(filepath.Join(dir, acceptedRootsDir)))
+}
+
+func printText(changes []ctloglists.AcceptedRootsChange) {
+	if len(changes) == 0 {
+		fmt.Printf("No logs' Accepted Roots have changed.\n")
+		return
+	}
+	for i, change := range changes {
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		if summary, ok := ctloglists.SummarizeLog(change.LogID); ok {
+			fmt.Printf("%s [%s] (%s)\n", summary.Description, summary.Operator, hex.EncodeToString(change.LogID[:]))
+		} else {
+			fmt.Printf("Unknown log (%s)\n", hex.EncodeToString(change.LogID[:]))
+		}
+		for _, root := range change.Added {
+			fmt.Printf("  + %s %s\n", hex.EncodeToString(root.Fingerprint[:]), root.Subject)
+		}
+		for _, root := range change.Removed {
+			fmt.Printf("  - %s %s\n", hex.EncodeToString(root.Fingerprint[:]), root.Subject)
+		}
+		for _, qr := range change.Quarantined {
+			fmt.Printf("  ! %s #%d quarantined: %s\n", qr.Source, qr.Index, qr.Error)
+		}
+	}
+}
+
+func printJSON(changes []ctloglists.AcceptedRootsChange) {
+	output := []changeJSON{}
+	for _, change := range changes {
+		cj := changeJSON{LogID: hex.EncodeToString(change.LogID[:]), Added: []rootJSON{}, Removed: []rootJSON{}}
+		if summary, ok := ctloglists.SummarizeLog(change.LogID); ok {
+			cj.Description, cj.Operator, cj.URL = summary.Description, summary.Operator, summary.URL
+		}
+		for _, root := range change.Added {
+			cj.Added = append(cj.Added, rootJSON{Fingerprint: hex.EncodeToString(root.Fingerprint[:]), Subject: root.Subject, NotAfter: root.NotAfter})
+		}
+		for _, root := range change.Removed {
+			cj.Removed = append(cj.Removed, rootJSON{Fingerprint: hex.EncodeToString(root.Fingerprint[:]), Subject: root.Subject, NotAfter: root.NotAfter})
+		}
+		cj.Quarantined = change.Quarantined
+		output = append(output, cj)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(output)
+}
@@ -2,170 +2,214 @@ package main
 
 import (
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/internal/loglistsource"
 
 	"github.com/google/certificate-transparency-go/loglist3"
 )
 
 func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs diffloglists with the command line arguments args, returning its exit status: 0 if no differences were found, 2 if differences were found, or 1 on error.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "Output format: text or json")
+	fields := flags.String("fields", strings.Join(defaultFields, ","), "Comma-separated list of fields to compare between logs present in both lists, or all: "+strings.Join(ctloglists.DiffFields, ","))
+	statuses := flags.String("status", "", "Comma-separated list of log statuses (e.g. usable,qualified) to report differences for")
+	types := flags.String("type", "", "Comma-separated list of log types (e.g. prod,test) to report differences for; prod matches logs without a type")
+	operators := flags.String("operator", "", "Comma-separated list of operators to report differences for")
+	categories := flags.String("category", "", "Comma-separated list of categories of difference to report: "+strings.Join(ctloglists.DiffCategories, ","))
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s [flags] <loglist1> <loglist2>\n", os.Args[0])
+		fmt.Fprintf(stderr, "Each log list is a bundled log list name, <name>@<git revision>, an HTTP(S) URL, or a local file.\n")
+		fmt.Fprintf(stderr, "Bundled log list names: %s\n", strings.Join(ctloglists.LogListNames, " "))
+		fmt.Fprintf(stderr, "Exits with status 0 if no differences were found, 2 if differences were found, or 1 on error.\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		// The flag package's own exit status for a bad flag (2) would be mistaken for differences.
+		return 1
+	}
+
 	// Load log lists.
 	if err := ctloglists.LoadLogLists(); err != nil {
-		panic(err)
-	}
-
-	// Define allowed log lists.
-	logListNames := map[string]*loglist3.LogList{
-		"gstatic-all":   ctloglists.GstaticV3All,
-		"apple-current": ctloglists.AppleCurrent,
-		"crtsh-all":     ctloglists.CrtshV3All,
-		"crtsh-active":  ctloglists.CrtshV3Active,
-		"mozilla-known": ctloglists.MozillaV3Known,
-		"bimi-approved": ctloglists.BimiV3Approved,
-		"log-mimics":    ctloglists.LogMimics,
-	}
-
-	// Use two required positional arguments instead of flags.
-	if len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <loglist1> <loglist2>\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Allowed values: ")
-		for k := range logListNames {
-			fmt.Fprintf(os.Stderr, "%s ", k)
-		}
-		fmt.Fprintf(os.Stderr, "\n")
-		os.Exit(1)
-	}
-	arg1 := os.Args[1]
-	arg2 := os.Args[2]
-	ll1, ok1 := logListNames[arg1]
-	ll2, ok2 := logListNames[arg2]
-	if !ok1 || !ok2 {
-		fmt.Fprintf(os.Stderr, "Error: unknown log list name(s).\n")
-		fmt.Fprintf(os.Stderr, "Allowed values: ")
-		for k := range logListNames {
-			fmt.Fprintf(os.Stderr, "%s ", k)
-		}
-		fmt.Fprintf(os.Stderr, "\n")
-		os.Exit(1)
-	}
-
-	fmt.Printf("Present in %s but not in %s:\n", arg1, arg2)
-	for _, operator := range ll1.Operators {
-		for _, log1 := range operator.Logs {
-			if log2 := ll2.FindLogByKey(log1.Key); log2 == nil {
-				logType := ""
-				if log1.Type != "" {
-					logType = fmt.Sprintf("[%s] ", log1.Type)
-				}
-				fmt.Printf("- %s%s; %s\n", logType, log1.URL, base64.StdEncoding.EncodeToString(log1.LogID))
-			}
-		}
-		for _, log1 := range operator.TiledLogs {
-			if log2 := ll2.FindTiledLogByKey(log1.Key); log2 == nil {
-				logType := ""
-				if log1.Type != "" {
-					logType = fmt.Sprintf("[%s] ", log1.Type)
-				}
-				fmt.Printf("- %s%s; %s\n", logType, log1.SubmissionURL, base64.StdEncoding.EncodeToString(log1.LogID))
-			}
-		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
 	}
 
-	fmt.Printf("\nPresent in %s but not in %s:\n", arg2, arg1)
-	for _, operator := range ll2.Operators {
-		for _, log2 := range operator.Logs {
-			if log1 := ll1.FindLogByKey(log2.Key); log1 == nil {
-				logType := ""
-				if log2.Type != "" {
-					logType = fmt.Sprintf("[%s] ", log2.Type)
-				}
-				fmt.Printf("- %s%s; %s\n", logType, log2.URL, base64.StdEncoding.EncodeToString(log2.LogID))
-			}
+	// Use two required positional arguments.
+	if flags.NArg() != 2 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return 1
+	}
+	var opts ctloglists.DiffOptions
+	var filter ctloglists.DiffFilter
+	var err error
+	if !strings.EqualFold(strings.TrimSpace(*fields), "all") {
+		if opts.Fields, err = splitList("field", *fields, ctloglists.DiffFields); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		} else if opts.Fields == nil {
+			opts.Fields = []string{}
 		}
-		for _, log2 := range operator.TiledLogs {
-			if log1 := ll1.FindTiledLogByKey(log2.Key); log1 == nil {
-				logType := ""
-				if log2.Type != "" {
-					logType = fmt.Sprintf("[%s] ", log2.Type)
-				}
-				fmt.Printf("- %s%s; %s\n", logType, log2.SubmissionURL, base64.StdEncoding.EncodeToString(log2.LogID))
-			}
+	}
+	for _, f := range []struct {
+		name, value string
+		allowed     []string
+		list        *[]string
+	}{
+		{"status", *statuses, logStatusNames, &filter.Statuses},
+		{"type", *types, nil, &filter.Types},
+		{"operator", *operators, nil, &filter.Operators},
+		{"category", *categories, ctloglists.DiffCategories, &filter.Categories},
+	} {
+		if *f.list, err = splitList(f.name, f.value, f.allowed); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
 		}
 	}
 
-	fmt.Printf("\nState differences between %s and %s:\n", arg1, arg2)
-	for _, operator := range ll1.Operators {
-		for _, log1 := range operator.Logs {
-			log2 := ll2.FindLogByKey(log1.Key)
-			if log2 != nil {
-				logType := ""
-				if log1.Type != "" {
-					logType = fmt.Sprintf("[%s] ", log1.Type)
-				}
-				state1 := log1.State.LogStatus().String()
-				state2 := log2.State.LogStatus().String()
-				if state1 != state2 {
-					fmt.Printf("- %s%s: %s vs %s\n", logType, log1.URL, strings.Replace(state1, "LogStatus", "", -1), strings.Replace(state2, "LogStatus", "", -1))
-				}
-			}
-		}
-		for _, log1 := range operator.TiledLogs {
-			log2 := ll2.FindTiledLogByKey(log1.Key)
-			if log2 != nil {
-				logType := ""
-				if log1.Type != "" {
-					logType = fmt.Sprintf("[%s] ", log1.Type)
-				}
-				state1 := log1.State.LogStatus().String()
-				state2 := log2.State.LogStatus().String()
-				if state1 != state2 {
-					fmt.Printf("- %s%s: %s vs %s\n", logType, log1.SubmissionURL, strings.Replace(state1, "LogStatus", "", -1), strings.Replace(state2, "LogStatus", "", -1))
-				}
+	arg1 := flags.Arg(0)
+	arg2 := flags.Arg(1)
+	ll1, err := loglistsource.Load(arg1)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	ll2, err := loglistsource.Load(arg2)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Log mimics aren't real logs, so ignore them unless the mimics list itself is being compared.
+	if !loglistsource.IsLogMimics(arg1) && !loglistsource.IsLogMimics(arg2) {
+		ll1 = ctloglists.WithoutLogMimics(ll1)
+		ll2 = ctloglists.WithoutLogMimics(ll2)
+	}
+
+	diff := ctloglists.DiffWithOptions(ll1, ll2, opts).Filter(filter)
+	switch *format {
+	case "json":
+		printJSON(stdout, arg1, arg2, diff)
+	default:
+		printText(stdout, arg1, arg2, diff)
+	}
+	if !diff.Empty() {
+		return 2
+	}
+	return 0
+}
+
+// defaultFields are the fields that diffloglists has always compared, so that its output doesn't grow unless more fields are requested.
+var defaultFields = []string{"state", "temporal_interval"}
+
+var logStatusNames = []string{"Pending", "Qualified", "Usable", "ReadOnly", "Retired", "Rejected", "Undefined"}
+
+// splitList splits a comma-separated flag value. If allowed is non-nil, each value is matched against allowed ignoring case and replaced by its spelling in allowed, returning an error if a value isn't one of allowed.
+func splitList(name, value string, allowed []string) ([]string, error) {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		} else if allowed != nil {
+			i := slices.IndexFunc(allowed, func(a string) bool { return strings.EqualFold(a, v) })
+			if i < 0 {
+				return nil, fmt.Errorf("unknown %s %q; allowed values: %s", name, v, strings.Join(allowed, ","))
 			}
+			v = allowed[i]
 		}
+		values = append(values, v)
 	}
+	return values, nil
+}
 
-	fmt.Printf("\nTemporal Period differences between %s and %s:\n", arg1, arg2)
-	for _, operator := range ll1.Operators {
-		for _, log1 := range operator.Logs {
-			log2 := ll2.FindLogByKey(log1.Key)
-			if log2 != nil {
-				logType := ""
-				if log1.Type != "" {
-					logType = fmt.Sprintf("[%s] ", log1.Type)
-				}
-				period1 := log1.TemporalInterval
-				period2 := log2.TemporalInterval
-				if !temporalIntervalsEqual(period1, period2) {
-					fmt.Printf("- %s%s: %s vs %s\n", logType, log1.URL, temporalIntervalString(period1), temporalIntervalString(period2))
-				}
-			}
+func printText(w io.Writer, arg1, arg2 string, diff ctloglists.ListDiff) {
+	fmt.Fprintf(w, "Present in %s but not in %s:\n", arg1, arg2)
+	for _, log := range diff.Removed {
+		fmt.Fprintf(w, "- %s%s; %s\n", logTypePrefix(log), log.URL, base64.StdEncoding.EncodeToString(log.LogID))
+	}
+
+	fmt.Fprintf(w, "\nPresent in %s but not in %s:\n", arg2, arg1)
+	for _, log := range diff.Added {
+		fmt.Fprintf(w, "- %s%s; %s\n", logTypePrefix(log), log.URL, base64.StdEncoding.EncodeToString(log.LogID))
+	}
+
+	fmt.Fprintf(w, "\nState differences between %s and %s:\n", arg1, arg2)
+	for _, change := range diff.StateChanges {
+		fmt.Fprintf(w, "- %s%s: %s vs %s\n", logTypePrefix(change.DiffLog), change.URL, change.From, change.To)
+	}
+
+	fmt.Fprintf(w, "\nTemporal Period differences between %s and %s:\n", arg1, arg2)
+	for _, change := range diff.TemporalIntervalChanges {
+		fmt.Fprintf(w, "- %s%s: %s vs %s\n", logTypePrefix(change.DiffLog), change.URL, temporalIntervalString(change.From), temporalIntervalString(change.To))
+	}
+
+	fmt.Fprintf(w, "\nField differences between %s and %s:\n", arg1, arg2)
+	for _, change := range diff.FieldChanges {
+		fmt.Fprintf(w, "- %s%s: %s: %s vs %s\n", logTypePrefix(change.DiffLog), change.URL, change.Field, fieldValueString(change.From), fieldValueString(change.To))
+	}
+
+	fmt.Fprintf(w, "\nOperator differences between %s and %s:\n", arg1, arg2)
+	for _, operator := range diff.Operators.Removed {
+		fmt.Fprintf(w, "- Present in %s but not in %s: %s\n", arg1, arg2, operator.Name)
+	}
+	for _, operator := range diff.Operators.Added {
+		fmt.Fprintf(w, "- Present in %s but not in %s: %s\n", arg2, arg1, operator.Name)
+	}
+	for _, rename := range diff.Operators.Renamed {
+		fmt.Fprintf(w, "- Renamed: %s vs %s\n", rename.From, rename.To)
+	}
+	for _, change := range diff.Operators.EmailChanges {
+		for _, email := range change.Removed {
+			fmt.Fprintf(w, "- %s: email %s present in %s but not in %s\n", change.Operator, email, arg1, arg2)
 		}
-		for _, log1 := range operator.TiledLogs {
-			log2 := ll2.FindTiledLogByKey(log1.Key)
-			if log2 != nil {
-				logType := ""
-				if log1.Type != "" {
-					logType = fmt.Sprintf("[%s] ", log1.Type)
-				}
-				period1 := log1.TemporalInterval
-				period2 := log2.TemporalInterval
-				if !temporalIntervalsEqual(period1, period2) {
-					fmt.Printf("- %s%s: %s vs %s\n", logType, log1.SubmissionURL, temporalIntervalString(period1), temporalIntervalString(period2))
-				}
-			}
+		for _, email := range change.Added {
+			fmt.Fprintf(w, "- %s: email %s present in %s but not in %s\n", change.Operator, email, arg2, arg1)
 		}
 	}
+	for _, move := range diff.Operators.LogMoves {
+		fmt.Fprintf(w, "- %s%s: operator %s vs %s\n", logTypePrefix(move.DiffLog), move.URL, move.FromOperator, move.ToOperator)
+	}
+}
+
+func fieldValueString(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+func printJSON(w io.Writer, arg1, arg2 string, diff ctloglists.ListDiff) {
+	output := struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		ctloglists.ListDiff
+	}{From: arg1, To: arg2, ListDiff: diff}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(output)
 }
 
-func temporalIntervalsEqual(a, b *loglist3.TemporalInterval) bool {
-	if a == nil || b == nil {
-		return a == b
+func logTypePrefix(log ctloglists.DiffLog) string {
+	if log.Type == "" {
+		return ""
 	}
-	return a.StartInclusive.Equal(b.StartInclusive) && a.EndExclusive.Equal(b.EndExclusive)
+	return fmt.Sprintf("[%s] ", log.Type)
 }
 
 func temporalIntervalString(ti *loglist3.TemporalInterval) string {
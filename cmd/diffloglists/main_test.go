@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// writeLogList writes a log list containing one operator's logs with the given statuses to a temporary file, returning its path.
+func writeLogList(t *testing.T, name string, states map[string]*loglist3.LogStates) string {
+	t.Helper()
+	operator := &loglist3.Operator{Name: "Operator"}
+	for _, description := range []string{"log1", "log2"} {
+		if state, ok := states[description]; ok {
+			logID := sha256.Sum256([]byte(description))
+			operator.Logs = append(operator.Logs, &loglist3.Log{Key: []byte(description), LogID: logID[:], Description: description, URL: "https://" + description + ".example/", MMD: 86400, State: state})
+		}
+	}
+	data, err := json.Marshal(&loglist3.LogList{Operators: []*loglist3.Operator{operator}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunExitStatus(t *testing.T) {
+	usable := &loglist3.LogStates{Usable: &loglist3.LogState{}}
+	retired := &loglist3.LogStates{Retired: &loglist3.LogState{}}
+	list := writeLogList(t, "list.json", map[string]*loglist3.LogStates{"log1": usable, "log2": usable})
+	retiring := writeLogList(t, "retiring.json", map[string]*loglist3.LogStates{"log1": usable, "log2": retired})
+	for _, test := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "identical", args: []string{list, list}, want: 0},
+		{name: "identical json", args: []string{"--format", "json", list, list}, want: 0},
+		{name: "different", args: []string{list, retiring}, want: 2},
+		{name: "different json", args: []string{"--format", "json", list, retiring}, want: 2},
+		{name: "differences filtered out", args: []string{"--category", "added,removed", list, retiring}, want: 0},
+		{name: "differences in other fields", args: []string{"--fields", "url", list, retiring}, want: 0},
+		{name: "help", args: []string{"-h"}, want: 0},
+		{name: "one log list", args: []string{list}, want: 1},
+		{name: "missing log list", args: []string{list, filepath.Join(t.TempDir(), "missing.json")}, want: 1},
+		{name: "unknown format", args: []string{"--format", "xml", list, list}, want: 1},
+		{name: "unknown flag", args: []string{"--unknown", list, list}, want: 1},
+		{name: "unknown status", args: []string{"--status", "unknown", list, list}, want: 1},
+		{name: "unknown field", args: []string{"--fields", "unknown", list, list}, want: 1},
+	} {
+		var stdout, stderr bytes.Buffer
+		if got := run(test.args, &stdout, &stderr); got != test.want {
+			t.Errorf("%s: run(%q) = %d, want %d; stderr: %s", test.name, test.args, got, test.want, stderr.String())
+		}
+		if test.want == 1 && stderr.Len() == 0 {
+			t.Errorf("%s: run(%q) reported no error", test.name, test.args)
+		}
+	}
+}
@@ -1,25 +1,649 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
 	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/crtsh/ctloglists"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
 )
 
+// listAliases maps user agents' names to the bundled log lists that represent their CT policies.
+var listAliases = map[string]string{
+	"chrome":  "gstatic-all",
+	"apple":   "apple-current",
+	"mozilla": "mozilla-known",
+}
+
+// logRoots describes a log and its Accepted Roots.
+type logRoots struct {
+	LogID     [sha256.Size]byte
+	Summary   ctloglists.LogSummary
+	Known     bool     // False if the log isn't in any bundled log list, in which case Summary is empty.
+	States    []string // "<list>: <status>" for each bundled log list that includes the log.
+	RootsList [sha256.Size]byte
+	Roots     []ctloglists.AcceptedRoot // Ordered by subject and fingerprint; nil if the roots list couldn't be loaded.
+}
+
+type rootJSON struct {
+	Fingerprint string    `json:"sha256_fingerprint"`
+	Subject     string    `json:"subject"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+type logSummaryJSON struct {
+	LogID       string            `json:"log_id"`
+	Description string            `json:"description,omitempty"`
+	Operator    string            `json:"operator,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	States      map[string]string `json:"states"`
+}
+
+type logJSON struct {
+	logSummaryJSON
+	RootsList string     `json:"roots_list"`
+	Roots     []rootJSON `json:"roots"`
+}
+
+func newLogSummaryJSON(lr logRoots) logSummaryJSON {
+	lsj := logSummaryJSON{LogID: hex.EncodeToString(lr.LogID[:]), Description: lr.Summary.Description, Operator: lr.Summary.Operator, URL: lr.Summary.URL, States: make(map[string]string)}
+	for _, state := range lr.States {
+		name, status, _ := strings.Cut(state, ": ")
+		lsj.States[name] = status
+	}
+	return lsj
+}
+
 func main() {
+	root := flag.String("root", "", "List the logs that accept this root, given as a SHA-256 certificate fingerprint, SHA-256 SPKI hash, or PEM/DER certificate file; can't be combined with --list, --operator, --log, --view or --output-dir")
+	logs := flag.String("log", "", "Comma-separated list of logs to list, each given as a log ID (hex or base64) or a case-insensitive substring of the log's description or URL")
+	operators := flag.String("operator", "", "Comma-separated list of operators whose logs to list")
+	list := flag.String("list", "", "Only list the logs in this bundled log list (or chrome, apple or mozilla), with their states in it")
+	view := flag.String("view", "logs", "View: logs (each log's Accepted Roots), grouped (each distinct Accepted Roots list once, with the logs that use it) or matrix (which roots differ between the distinct Accepted Roots lists)")
+	format := flag.String("format", "text", "Output format: text, json, csv or pem (pem is only supported by the logs view, and not with --root)")
+	outputDir := flag.String("output-dir", "", "Directory to write a PEM bundle per log to, named <log ID>.pem (required with --format pem)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--list <name>] [--operator <names>] [--log <logs>] [--view logs|grouped|matrix] [--format text|json|csv|pem] [--output-dir <dir>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s --root <fingerprint|file> [--format text|json|csv]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Lists each log's Accepted Roots, with the log's description, operator, URL and states from the bundled log lists, or with --root, the logs that accept the specified root.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	if err := ctloglists.LoadAcceptedRoots(); err != nil {
 		panic(err)
 	}
+	if err := ctloglists.LoadLogLists(); err != nil {
+		panic(err)
+	}
+
+	if *root != "" {
+		// The filters, views and PEM bundles only apply to listing each log's Accepted Roots.
+		var conflicting []string
+		flag.Visit(func(f *flag.Flag) {
+			if slices.Contains([]string{"log", "operator", "list", "view", "output-dir"}, f.Name) {
+				conflicting = append(conflicting, "--"+f.Name)
+			}
+		})
+		if len(conflicting) > 0 || !slices.Contains([]string{"text", "json", "csv"}, *format) {
+			flag.Usage()
+			os.Exit(1)
+		}
+		fingerprint, err := parseRootArg(*root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err = listLogsAcceptingRoot(fingerprint, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !slices.Contains([]string{"logs", "grouped", "matrix"}, *view) || !slices.Contains([]string{"text", "json", "csv", "pem"}, *format) || (*format == "pem") != (*outputDir != "") || (*format == "pem" && *view != "logs") {
+		flag.Usage()
+		os.Exit(1)
+	}
+	listNames := ctloglists.LogListNames
+	if *list != "" {
+		if alias, ok := listAliases[strings.ToLower(*list)]; ok {
+			*list = alias
+		}
+		if ctloglists.LogListByName(*list) == nil {
+			fmt.Fprintf(os.Stderr, "Error: unknown log list %q\n", *list)
+			os.Exit(1)
+		}
+		listNames = []string{*list}
+	}
+
+	selected := selectLogs(listNames, *list != "", splitList(*logs), splitList(*operators))
+	var err error
+	switch *view + "/" + *format {
+	case "logs/json":
+		err = printJSON(selected)
+	case "logs/csv":
+		err = printCSV(selected)
+	case "logs/pem":
+		err = writePEMBundles(selected, *outputDir)
+	case "grouped/text":
+		printGroupedText(groupLogs(selected))
+	case "grouped/json":
+		err = printGroupedJSON(groupLogs(selected))
+	case "grouped/csv":
+		err = printGroupedCSV(groupLogs(selected))
+	case "matrix/text":
+		printMatrixText(groupLogs(selected))
+	case "matrix/json":
+		err = printMatrixJSON(groupLogs(selected))
+	case "matrix/csv":
+		err = printMatrixCSV(groupLogs(selected))
+	default:
+		printText(selected)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// selectLogs returns the logs with known Accepted Roots that match the filters, ordered by operator, description and log ID.
+// If inListOnly is true, only the logs included in the first of listNames are selected.
+func selectLogs(listNames []string, inListOnly bool, logFilters, operatorFilters []string) []logRoots {
+	var logLists []*loglist3.LogList
+	for _, name := range listNames {
+		logLists = append(logLists, ctloglists.LogListByName(name))
+	}
+	summaries := ctloglists.SummarizeLogs(logLists...)
+
+	var selected []logRoots
+	for logID, rootsListHash := range ctloglists.LogAcceptedRootsMap {
+		summary, known := summaries[logID]
+		if inListOnly && !known {
+			continue
+		} else if len(operatorFilters) > 0 && !slices.ContainsFunc(operatorFilters, func(o string) bool { return strings.EqualFold(o, summary.Operator) }) {
+			continue
+		} else if len(logFilters) > 0 && !slices.ContainsFunc(logFilters, func(f string) bool { return matchesLog(f, logID, summary) }) {
+			continue
+		}
+
+		lr := logRoots{LogID: logID, Summary: summary, Known: known, States: logStates(logID, listNames), RootsList: rootsListHash}
+		if ctloglists.AcceptedRootsMap[rootsListHash] != nil {
+			lr.Roots = slices.Clone(ctloglists.AcceptedRootsForLog(logID))
+			slices.SortFunc(lr.Roots, func(a, b ctloglists.AcceptedRoot) int {
+				if c := strings.Compare(a.Subject, b.Subject); c != 0 {
+					return c
+				}
+				return bytes.Compare(a.Fingerprint[:], b.Fingerprint[:])
+			})
+		}
+		selected = append(selected, lr)
+	}
+
+	// Logs that aren't in any of the log lists sort last.
+	slices.SortFunc(selected, func(a, b logRoots) int {
+		if a.Known != b.Known {
+			if a.Known {
+				return -1
+			}
+			return 1
+		} else if c := strings.Compare(a.Summary.Operator, b.Summary.Operator); c != 0 {
+			return c
+		} else if c = strings.Compare(a.Summary.Description, b.Summary.Description); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.LogID[:], b.LogID[:])
+	})
+	return selected
+}
+
+// matchesLog returns true if filter is the log's ID (in hex or base64) or a case-insensitive substring of its description or URL.
+func matchesLog(filter string, logID [sha256.Size]byte, summary ctloglists.LogSummary) bool {
+	if strings.EqualFold(filter, hex.EncodeToString(logID[:])) || filter == base64.StdEncoding.EncodeToString(logID[:]) {
+		return true
+	}
+	filter = strings.ToLower(filter)
+	return (summary.Description != "" && strings.Contains(strings.ToLower(summary.Description), filter)) || (summary.URL != "" && strings.Contains(strings.ToLower(summary.URL), filter))
+}
+
+func printText(selected []logRoots) {
+	for i, lr := range selected {
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		if lr.Known {
+			fmt.Printf("%s [%s]\n", lr.Summary.Description, lr.Summary.Operator)
+			fmt.Printf("  ID: %s\n", hex.EncodeToString(lr.LogID[:]))
+			fmt.Printf("  URL: %s\n", lr.Summary.URL)
+		} else {
+			fmt.Printf("Unknown log\n")
+			fmt.Printf("  ID: %s\n", hex.EncodeToString(lr.LogID[:]))
+		}
+		for _, state := range lr.States {
+			fmt.Printf("  %s\n", state)
+		}
+		if lr.Roots == nil {
+			fmt.Printf("  No accepted roots found\n")
+			continue
+		}
+		fmt.Printf("  Accepted Roots (%d, roots list %s):\n", len(lr.Roots), hex.EncodeToString(lr.RootsList[:]))
+		for _, root := range lr.Roots {
+			fmt.Printf("    %s %s\n", hex.EncodeToString(root.Fingerprint[:]), root.Subject)
+		}
+	}
+}
+
+func printJSON(selected []logRoots) error {
+	output := []logJSON{}
+	for _, lr := range selected {
+		lj := logJSON{logSummaryJSON: newLogSummaryJSON(lr), RootsList: hex.EncodeToString(lr.RootsList[:]), Roots: []rootJSON{}}
+		for _, root := range lr.Roots {
+			lj.Roots = append(lj.Roots, rootJSON{Fingerprint: hex.EncodeToString(root.Fingerprint[:]), Subject: root.Subject, NotAfter: root.NotAfter})
+		}
+		output = append(output, lj)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// printCSV prints one row per log and Accepted Root.
+func printCSV(selected []logRoots) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"log_id", "description", "operator", "url", "states", "roots_list", "sha256_fingerprint", "subject", "not_after"})
+	for _, lr := range selected {
+		for _, root := range lr.Roots {
+			w.Write([]string{hex.EncodeToString(lr.LogID[:]), lr.Summary.Description, lr.Summary.Operator, lr.Summary.URL, strings.Join(lr.States, "; "), hex.EncodeToString(lr.RootsList[:]), hex.EncodeToString(root.Fingerprint[:]), root.Subject, root.NotAfter.Format(time.RFC3339)})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writePEMBundles writes each log's Accepted Roots to <dir>/<log ID>.pem, preceding each certificate with its subject.
+func writePEMBundles(selected []logRoots, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, lr := range selected {
+		if lr.Roots == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if lr.Known {
+			fmt.Fprintf(&buf, "# %s [%s]\n", lr.Summary.Description, lr.Summary.Operator)
+		}
+		for _, root := range lr.Roots {
+			fmt.Fprintf(&buf, "\n# %s\n# SHA-256 fingerprint: %s\n", root.Subject, hex.EncodeToString(root.Fingerprint[:]))
+			pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: root.DER})
+		}
+		if err := os.WriteFile(filepath.Join(dir, hex.EncodeToString(lr.LogID[:])+".pem"), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRootArg returns the SHA-256 fingerprint (or SPKI hash) specified directly as hex, or else the SHA-256 fingerprint of the certificate in the specified file.
+func parseRootArg(arg string) ([sha256.Size]byte, error) {
+	var fingerprint [sha256.Size]byte
+	if decoded, err := hex.DecodeString(strings.ReplaceAll(arg, ":", "")); err == nil && len(decoded) == sha256.Size {
+		copy(fingerprint[:], decoded)
+		return fingerprint, nil
+	}
+
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return fingerprint, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	if _, err = x509.ParseCertificate(data); x509.IsFatal(err) {
+		return fingerprint, fmt.Errorf("failed to parse certificate from %s: %v", arg, err)
+	}
+	return sha256.Sum256(data), nil
+}
+
+// listLogsAcceptingRoot prints the logs that accept the root with the specified fingerprint (or SPKI hash), with their states in each bundled log list.
+func listLogsAcceptingRoot(fingerprint [sha256.Size]byte, format string) error {
+	var accepting []logRoots
+	for _, logID := range ctloglists.LogsAcceptingRoot(fingerprint) {
+		summary, known := ctloglists.SummarizeLog(logID)
+		accepting = append(accepting, logRoots{LogID: logID, Summary: summary, Known: known, States: logStates(logID, ctloglists.LogListNames)})
+	}
+
+	switch format {
+	case "json":
+		output := struct {
+			Root string           `json:"root"`
+			Logs []logSummaryJSON `json:"logs"`
+		}{Root: hex.EncodeToString(fingerprint[:]), Logs: []logSummaryJSON{}}
+		for _, lr := range accepting {
+			output.Logs = append(output.Logs, newLogSummaryJSON(lr))
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"log_id", "description", "operator", "url", "states"})
+		for _, lr := range accepting {
+			w.Write([]string{hex.EncodeToString(lr.LogID[:]), lr.Summary.Description, lr.Summary.Operator, lr.Summary.URL, strings.Join(lr.States, "; ")})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Printf("%d log(s) accept root %s:\n", len(accepting), hex.EncodeToString(fingerprint[:]))
+	for _, lr := range accepting {
+		fmt.Printf("\n%s\n", hex.EncodeToString(lr.LogID[:]))
+		if lr.Known {
+			fmt.Printf("  %s [%s] (%s)\n", lr.Summary.Description, lr.Summary.Operator, lr.Summary.URL)
+		}
+		for _, state := range lr.States {
+			fmt.Printf("  %s\n", state)
+		}
+	}
+	return nil
+}
+
+// logStates returns the log's state in each of the named log lists that includes it.
+func logStates(logID [sha256.Size]byte, listNames []string) []string {
+	var states []string
+	for _, name := range listNames {
+		logList := ctloglists.LogListByName(name)
+		if logList == nil {
+			continue
+		}
+		var logStates *loglist3.LogStates
+		if log := logList.FindLogByKeyHash(logID); log != nil {
+			logStates = log.State
+		} else if tiledLog := logList.FindTiledLogByKeyHash(logID); tiledLog != nil {
+			logStates = tiledLog.State
+		} else {
+			continue
+		}
+		states = append(states, fmt.Sprintf("%s: %s", name, ctloglists.LogStatusName(logStates.LogStatus())))
+	}
+	return states
+}
+
+// rootsGroup is a distinct Accepted Roots list, and the logs that use it.
+type rootsGroup struct {
+	Label     string // "G1", "G2", etc.
+	RootsList [sha256.Size]byte
+	Logs      []logRoots
+	Roots     []ctloglists.AcceptedRoot // nil if the roots list couldn't be loaded.
+}
+
+type groupJSON struct {
+	Label     string           `json:"label"`
+	RootsList string           `json:"roots_list"`
+	Logs      []logSummaryJSON `json:"logs"`
+	Roots     []rootJSON       `json:"roots,omitempty"`
+}
+
+// groupLogs groups the selected logs by the Accepted Roots list that they use, ordered by descending number of logs and then by roots list hash.
+func groupLogs(selected []logRoots) []rootsGroup {
+	var groups []rootsGroup
+	groupByRootsList := make(map[[sha256.Size]byte]int)
+	for _, lr := range selected {
+		i, ok := groupByRootsList[lr.RootsList]
+		if !ok {
+			i = len(groups)
+			groupByRootsList[lr.RootsList] = i
+			groups = append(groups, rootsGroup{RootsList: lr.RootsList, Roots: lr.Roots})
+		}
+		groups[i].Logs = append(groups[i].Logs, lr)
+	}
+	slices.SortFunc(groups, func(a, b rootsGroup) int {
+		if len(a.Logs) != len(b.Logs) {
+			return len(b.Logs) - len(a.Logs)
+		}
+		return bytes.Compare(a.RootsList[:], b.RootsList[:])
+	})
+	for i := range groups {
+		groups[i].Label = fmt.Sprintf("G%d", i+1)
+	}
+	return groups
+}
+
+func logName(lr logRoots) string {
+	if !lr.Known {
+		return "Unknown log (" + hex.EncodeToString(lr.LogID[:]) + ")"
+	}
+	return fmt.Sprintf("%s [%s] (%s)", lr.Summary.Description, lr.Summary.Operator, hex.EncodeToString(lr.LogID[:]))
+}
+
+func printGroupHeader(group rootsGroup) {
+	if group.Roots == nil {
+		fmt.Printf("%s: roots list %s (not found), used by %d log(s):\n", group.Label, hex.EncodeToString(group.RootsList[:]), len(group.Logs))
+	} else {
+		fmt.Printf("%s: roots list %s (%d roots), used by %d log(s):\n", group.Label, hex.EncodeToString(group.RootsList[:]), len(group.Roots), len(group.Logs))
+	}
+	for _, lr := range group.Logs {
+		fmt.Printf("  %s\n", logName(lr))
+	}
+}
+
+func printGroupedText(groups []rootsGroup) {
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		printGroupHeader(group)
+		if group.Roots != nil {
+			fmt.Printf("  Accepted Roots:\n")
+		}
+		for _, root := range group.Roots {
+			fmt.Printf("    %s %s\n", hex.EncodeToString(root.Fingerprint[:]), root.Subject)
+		}
+	}
+}
+
+func newGroupJSON(group rootsGroup, includeRoots bool) groupJSON {
+	gj := groupJSON{Label: group.Label, RootsList: hex.EncodeToString(group.RootsList[:]), Logs: []logSummaryJSON{}}
+	for _, lr := range group.Logs {
+		gj.Logs = append(gj.Logs, newLogSummaryJSON(lr))
+	}
+	if includeRoots {
+		gj.Roots = []rootJSON{}
+		for _, root := range group.Roots {
+			gj.Roots = append(gj.Roots, rootJSON{Fingerprint: hex.EncodeToString(root.Fingerprint[:]), Subject: root.Subject, NotAfter: root.NotAfter})
+		}
+	}
+	return gj
+}
+
+func printGroupedJSON(groups []rootsGroup) error {
+	output := []groupJSON{}
+	for _, group := range groups {
+		output = append(output, newGroupJSON(group, true))
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// printGroupedCSV prints one row per distinct Accepted Roots list and root, listing the logs that use the roots list.
+func printGroupedCSV(groups []rootsGroup) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"group", "roots_list", "log_ids", "sha256_fingerprint", "subject", "not_after"})
+	for _, group := range groups {
+		var logIDs []string
+		for _, lr := range group.Logs {
+			logIDs = append(logIDs, hex.EncodeToString(lr.LogID[:]))
+		}
+		for _, root := range group.Roots {
+			w.Write([]string{group.Label, hex.EncodeToString(group.RootsList[:]), strings.Join(logIDs, " "), hex.EncodeToString(root.Fingerprint[:]), root.Subject, root.NotAfter.Format(time.RFC3339)})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
 
-	for logID, rootListHash := range ctloglists.LogAcceptedRootsMap {
-		if ctloglists.AcceptedRootsMap[rootListHash] == nil {
-			fmt.Printf("No accepted roots found for log with ID %s\n", hex.EncodeToString(logID[:]))
+// rootMembership is a root that's accepted by some, but not all, of the groups whose roots lists could be loaded.
+type rootMembership struct {
+	Root     ctloglists.AcceptedRoot
+	InGroups []bool // Indexed as the groups.
+}
+
+// differingRoots returns the roots accepted by some, but not all, of the groups whose roots lists could be loaded (ordered by subject and fingerprint), and the number of roots accepted by all of them.
+func differingRoots(groups []rootsGroup) ([]rootMembership, int) {
+	var memberships []*rootMembership
+	byFingerprint := make(map[[sha256.Size]byte]*rootMembership)
+	loadedGroups := 0
+	for i, group := range groups {
+		if group.Roots == nil {
+			continue
+		}
+		loadedGroups++
+		for _, root := range group.Roots {
+			rm, ok := byFingerprint[root.Fingerprint]
+			if !ok {
+				rm = &rootMembership{Root: root, InGroups: make([]bool, len(groups))}
+				byFingerprint[root.Fingerprint] = rm
+				memberships = append(memberships, rm)
+			}
+			rm.InGroups[i] = true
+		}
+	}
+
+	var differing []rootMembership
+	common := 0
+	for _, rm := range memberships {
+		n := 0
+		for _, in := range rm.InGroups {
+			if in {
+				n++
+			}
+		}
+		if n == loadedGroups {
+			common++
 		} else {
-			fmt.Printf("\n%s (%s)\n", hex.EncodeToString(logID[:]), hex.EncodeToString(rootListHash[:]))
-			for _, cert := range ctloglists.AcceptedRootsMap[rootListHash].RawCertificates() {
-				fmt.Printf("  %s\n", cert.Subject.String())
+			differing = append(differing, *rm)
+		}
+	}
+	slices.SortFunc(differing, func(a, b rootMembership) int {
+		if c := strings.Compare(a.Root.Subject, b.Root.Subject); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.Root.Fingerprint[:], b.Root.Fingerprint[:])
+	})
+	return differing, common
+}
+
+func printMatrixText(groups []rootsGroup) {
+	for _, group := range groups {
+		printGroupHeader(group)
+		fmt.Printf("\n")
+	}
+
+	differing, common := differingRoots(groups)
+	fmt.Printf("%d root(s) are accepted by every group; %d root(s) are accepted by only some groups:\n", common, len(differing))
+	if len(differing) == 0 {
+		return
+	}
+	width := len(groups[len(groups)-1].Label) + 1
+	for _, group := range groups {
+		fmt.Printf("%-*s", width, group.Label)
+	}
+	fmt.Printf("Root\n")
+	for _, rm := range differing {
+		for i, group := range groups {
+			mark := "."
+			if group.Roots == nil {
+				mark = "?"
+			} else if rm.InGroups[i] {
+				mark = "X"
+			}
+			fmt.Printf("%-*s", width, mark)
+		}
+		fmt.Printf("%s %s\n", hex.EncodeToString(rm.Root.Fingerprint[:]), rm.Root.Subject)
+	}
+}
+
+func printMatrixJSON(groups []rootsGroup) error {
+	type matrixRootJSON struct {
+		rootJSON
+		Groups []string `json:"groups"`
+	}
+	output := struct {
+		Groups      []groupJSON      `json:"groups"`
+		CommonRoots int              `json:"common_roots"`
+		Roots       []matrixRootJSON `json:"differing_roots"`
+	}{Groups: []groupJSON{}, Roots: []matrixRootJSON{}}
+	for _, group := range groups {
+		output.Groups = append(output.Groups, newGroupJSON(group, false))
+	}
+	var differing []rootMembership
+	differing, output.CommonRoots = differingRoots(groups)
+	for _, rm := range differing {
+		mrj := matrixRootJSON{rootJSON: rootJSON{Fingerprint: hex.EncodeToString(rm.Root.Fingerprint[:]), Subject: rm.Root.Subject, NotAfter: rm.Root.NotAfter}, Groups: []string{}}
+		for i, in := range rm.InGroups {
+			if in {
+				mrj.Groups = append(mrj.Groups, groups[i].Label)
+			}
+		}
+		output.Roots = append(output.Roots, mrj)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// printMatrixCSV prints one row per root accepted by only some groups, with a column per group.
+func printMatrixCSV(groups []rootsGroup) error {
+	w := csv.NewWriter(os.Stdout)
+	header := []string{"sha256_fingerprint", "subject", "not_after"}
+	for _, group := range groups {
+		header = append(header, group.Label+" ("+hex.EncodeToString(group.RootsList[:])+")")
+	}
+	w.Write(header)
+	differing, _ := differingRoots(groups)
+	for _, rm := range differing {
+		row := []string{hex.EncodeToString(rm.Root.Fingerprint[:]), rm.Root.Subject, rm.Root.NotAfter.Format(time.RFC3339)}
+		for i, group := range groups {
+			switch {
+			case group.Roots == nil:
+				row = append(row, "")
+			case rm.InGroups[i]:
+				row = append(row, "1")
+			default:
+				row = append(row, "0")
 			}
 		}
+		w.Write(row)
 	}
+	w.Flush()
+	return w.Error()
 }
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/internal/loglistsource"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+type listJSON struct {
+	Name        string `json:"name"`
+	Changed     bool   `json:"changed"`
+	Substantive bool   `json:"substantive"`
+	*ctloglists.ListChanges
+}
+
+func main() {
+	oldRev := flag.String("old", "HEAD", "Git revision of this repository to compare the working tree's log lists with")
+	format := flag.String("format", "text", "Output format: text or json")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--old <git revision>] [--format text|json]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Classifies the changes to each bundled log list in the working tree since the specified git revision.\n")
+		fmt.Fprintf(os.Stderr, "Exits with status 0 if there are no substantive changes, 2 if there are substantive changes, or 1 on error.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 || (*format != "text" && *format != "json") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	lists, err := classify(*oldRev)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	substantive := false
+	for _, list := range lists {
+		substantive = substantive || list.Substantive
+	}
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(lists)
+	default:
+		printText(lists, substantive)
+	}
+	if substantive {
+		os.Exit(2)
+	}
+}
+
+// classify compares each bundled log list in the working tree with its version as of oldRev.
+func classify(oldRev string) ([]listJSON, error) {
+	root, err := loglistsource.RepositoryRoot()
+	if err != nil {
+		return nil, err
+	} else if _, err = loglistsource.ResolveRevision(oldRev); err != nil {
+		return nil, err
+	}
+
+	var lists []listJSON
+	for _, name := range ctloglists.LogListNames {
+		filename := ctloglists.LogListFilename(name)
+		newData, err := os.ReadFile(filepath.Join(root, filename))
+		if err != nil {
+			return nil, err
+		}
+		newList, err := loglist3.NewFromJSON(newData)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+
+		// A log list that's new since oldRev is compared with an empty log list.
+		oldData, err := loglistsource.ReadFileAtRevision(oldRev, filename)
+		oldList := &loglist3.LogList{}
+		if errors.Is(err, fs.ErrNotExist) {
+			oldData = nil
+		} else if err != nil {
+			return nil, err
+		} else if oldList, err = loglist3.NewFromJSON(oldData); err != nil {
+			return nil, fmt.Errorf("%s:%s: %v", oldRev, filename, err)
+		}
+
+		list := listJSON{Name: name}
+		if oldData == nil || !bytes.Equal(oldData, newData) {
+			changes := ctloglists.ClassifyChanges(oldList, newList)
+			list.Changed, list.Substantive, list.ListChanges = true, changes.Substantive(), &changes
+		}
+		lists = append(lists, list)
+	}
+	return lists, nil
+}
+
+func printText(lists []listJSON, substantive bool) {
+	for _, list := range lists {
+		if !list.Changed {
+			fmt.Printf("%s: unchanged\n", list.Name)
+			continue
+		}
+		var kinds []string
+		for _, kind := range list.Kinds {
+			kinds = append(kinds, string(kind))
+		}
+		fmt.Printf("%s: %s\n", list.Name, strings.Join(kinds, ", "))
+		for _, dl := range list.Added {
+			fmt.Printf("  + %s [%s] (%s)\n", dl.Description, dl.Operator, base64.StdEncoding.EncodeToString(dl.LogID))
+		}
+		for _, dl := range list.Removed {
+			fmt.Printf("  - %s [%s] (%s)\n", dl.Description, dl.Operator, base64.StdEncoding.EncodeToString(dl.LogID))
+		}
+		for _, change := range list.KeyChanges {
+			fmt.Printf("  * %s [%s]: key changed (%s vs %s)\n", change.New.Description, change.New.Operator, base64.StdEncoding.EncodeToString(change.Old.LogID), base64.StdEncoding.EncodeToString(change.New.LogID))
+		}
+		for _, change := range list.StateChanges {
+			fmt.Printf("  * %s [%s]: %s vs %s\n", change.Description, change.Operator, change.From, change.To)
+		}
+	}
+
+	if substantive {
+		fmt.Printf("\nOne or more log lists have been updated substantively.\n")
+	} else {
+		fmt.Printf("\nNo log lists have been updated substantively.\n")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/crtsh/ctloglists"
+	"github.com/crtsh/ctloglists/internal/loglistsource"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+const acceptedRootsDir = "files/acceptedroots"
+
+func main() {
+	output := flag.String("output", "", "File to write the release notes to, instead of standard output")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--output <file>] <old> <new>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Writes Markdown release notes describing the changes to the bundled log lists and Accepted Roots between two git revisions of this repository.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	oldRev, newRev := flag.Arg(0), flag.Arg(1)
+
+	var notes bytes.Buffer
+	if err := writeReleaseNotes(&notes, oldRev, newRev); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(notes.Bytes())
+	} else if err := os.WriteFile(*output, notes.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// logNames maps log IDs to summaries of those logs, for naming the logs whose Accepted Roots changed.
+type logNames map[[sha256.Size]byte]ctloglists.LogSummary
+
+func (names logNames) name(logID [sha256.Size]byte) string {
+	if summary, ok := names[logID]; ok {
+		return logName(summary.Description, summary.Operator)
+	}
+	return "Unknown log `" + hex.EncodeToString(logID[:]) + "`"
+}
+
+func logName(description, operator string) string {
+	return fmt.Sprintf("%s (%s)", markdownEscape(description), markdownEscape(operator))
+}
+
+func writeReleaseNotes(w io.Writer, oldRev, newRev string) error {
+	// Log lists.
+	fmt.Fprintf(w, "## Log lists\n")
+	var newLists, oldLists []*loglist3.LogList
+	anyChanges := false
+	for _, name := range ctloglists.LogListNames {
+		oldList, err := loglistsource.LoadAtRevision(oldRev, name)
+		if err != nil {
+			return err
+		}
+		newList, err := loglistsource.LoadAtRevision(newRev, name)
+		if err != nil {
+			return err
+		}
+		oldLists, newLists = append(oldLists, oldList), append(newLists, newList)
+
+		changes := ctloglists.ClassifyChanges(oldList, newList)
+		if !changes.Substantive() {
+			continue
+		}
+		anyChanges = true
+		fmt.Fprintf(w, "\n### %s\n\n", name)
+		for _, dl := range changes.Added {
+			fmt.Fprintf(w, "- Added %s\n", diffLogString(dl))
+		}
+		for _, dl := range changes.Removed {
+			fmt.Fprintf(w, "- Removed %s\n", diffLogString(dl))
+		}
+		for _, change := range changes.KeyChanges {
+			fmt.Fprintf(w, "- Key changed for %s: log ID `%s` → `%s`\n", logName(change.New.Description, change.New.Operator), base64.StdEncoding.EncodeToString(change.Old.LogID), base64.StdEncoding.EncodeToString(change.New.LogID))
+		}
+		for _, change := range changes.StateChanges {
+			fmt.Fprintf(w, "- %s: %s → %s\n", logName(change.Description, change.Operator), change.From, change.To)
+		}
+		if slices.Contains(changes.Kinds, ctloglists.ChangeOther) {
+			fmt.Fprintf(w, "- Other changes to logs or operators; see `diffloglists %s@%s %s@%s`\n", name, oldRev, name, newRev)
+		}
+	}
+	if !anyChanges {
+		fmt.Fprintf(w, "\nNo substantive changes.\n")
+	}
+
+	// Name logs as they appear in the new log lists, or else as they appeared in the old log lists.
+	names := logNames(ctloglists.SummarizeLogs(append(newLists, oldLists...)...))
+
+	// Accepted Roots.
+	fmt.Fprintf(w, "\n## Accepted Roots\n")
+	oldRoots, oldQuarantined, err := readAcceptedRoots(oldRev)
+	if err != nil {
+		return err
+	}
+	newRoots, newQuarantined, err := readAcceptedRoots(newRev)
+	if err != nil {
+		return err
+	}
+	groups := groupChanges(ctloglists.DiffAcceptedRoots(oldRoots, newRoots, oldQuarantined, newQuarantined), names)
+	if len(groups) == 0 {
+		fmt.Fprintf(w, "\nNo changes.\n")
+	}
+	for _, group := range groups {
+		fmt.Fprintf(w, "\n%s:\n\n", strings.Join(group.logs, "; "))
+		for _, root := range group.change.Added {
+			fmt.Fprintf(w, "- Added `%s` %s\n", hex.EncodeToString(root.Fingerprint[:]), markdownEscape(root.Subject))
+		}
+		for _, root := range group.change.Removed {
+			fmt.Fprintf(w, "- Removed `%s` %s\n", hex.EncodeToString(root.Fingerprint[:]), markdownEscape(root.Subject))
+		}
+		for _, qr := range group.change.Quarantined {
+			fmt.Fprintf(w, "- Quarantined certificate %d from `%s`: %s\n", qr.Index, qr.Source, markdownEscape(qr.Error))
+		}
+	}
+	return nil
+}
+
+// readAcceptedRoots reads the Accepted Roots, and the certificates quarantined from them, as of git revision rev. If they didn't exist as of rev, no logs have any Accepted Roots.
+func readAcceptedRoots(rev string) (ctloglists.AcceptedRootsSet, map[[sha256.Size]byte][]ctloglists.QuarantinedRoot, error) {
+	dir, err := loglistsource.ExtractDirectoryAtRevision(rev, acceptedRootsDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ctloglists.AcceptedRootsSet{}, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+	return ctloglists.ReadAcceptedRoots(os.DirFS(filepath.Join(dir, acceptedRootsDir)))
+}
+
+// changeGroup is a set of logs whose Accepted Roots changed identically.
+type changeGroup struct {
+	logs   []string
+	change ctloglists.AcceptedRootsChange
+}
+
+// groupChanges groups logs whose Accepted Roots changed identically (which is typical when an operator updates the roots of all of its logs at once), ordering the groups by their first log's name.
+func groupChanges(changes []ctloglists.AcceptedRootsChange, names logNames) []changeGroup {
+	var groups []changeGroup
+	groupByKey := make(map[string]int)
+	for _, change := range changes {
+		var key strings.Builder
+		for _, root := range change.Added {
+			key.WriteString("+" + hex.EncodeToString(root.Fingerprint[:]))
+		}
+		for _, root := range change.Removed {
+			key.WriteString("-" + hex.EncodeToString(root.Fingerprint[:]))
+		}
+		for _, qr := range change.Quarantined {
+			key.WriteString(fmt.Sprintf("!%s#%d:%x", qr.Source, qr.Index, sha256.Sum256(qr.Raw)))
+		}
+		if i, ok := groupByKey[key.String()]; ok {
+			groups[i].logs = append(groups[i].logs, names.name(change.LogID))
+		} else {
+			groupByKey[key.String()] = len(groups)
+			groups = append(groups, changeGroup{logs: []string{names.name(change.LogID)}, change: change})
+		}
+	}
+	for i := range groups {
+		slices.Sort(groups[i].logs)
+	}
+	slices.SortFunc(groups, func(a, b changeGroup) int {
+		return strings.Compare(a.logs[0], b.logs[0])
+	})
+	return groups
+}
+
+func diffLogString(dl ctloglists.DiffLog) string {
+	s := logName(dl.Description, dl.Operator)
+	if dl.Status != "" {
+		s += " [" + dl.Status + "]"
+	}
+	return fmt.Sprintf("%s `%s`, log ID `%s`", s, dl.URL, base64.StdEncoding.EncodeToString(dl.LogID))
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
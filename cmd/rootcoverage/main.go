@@ -0,0 +1,261 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crtsh/ctloglists"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// userAgents maps each user agent to the bundled log list that represents its CT policy.
+var userAgents = []struct {
+	name     string
+	listName string
+}{
+	{"chrome", "gstatic-all"},
+	{"apple", "apple-current"},
+	{"mozilla", "mozilla-known"},
+}
+
+const unsharded = "unsharded"
+
+type root struct {
+	Fingerprint [sha256.Size]byte
+	Subject     string // Empty if only the fingerprint was specified.
+}
+
+type coverageJSON struct {
+	Fingerprint string                  `json:"sha256_fingerprint"`
+	Subject     string                  `json:"subject,omitempty"`
+	UserAgents  []userAgentCoverageJSON `json:"user_agents"`
+}
+
+type userAgentCoverageJSON struct {
+	UserAgent     string         `json:"user_agent"`
+	LogList       string         `json:"log_list"`
+	Logs          int            `json:"logs"`
+	Operators     []string       `json:"operators"`
+	ShardsPerYear map[string]int `json:"shards_per_expiry_year"`
+}
+
+func main() {
+	format := flag.String("format", "text", "Output format: text or json")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [--format text|json] <root>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Each <root> is a SHA-256 certificate fingerprint, or a file containing one or more PEM certificates or a DER certificate.\n")
+		fmt.Fprintf(os.Stderr, "For each root, reports how many Usable or Qualified logs in each user agent's log list accept it, run by how many distinct operators, and how many of those logs are temporal shards for each expiry year.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || (*format != "text" && *format != "json") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := ctloglists.LoadLogLists(); err != nil {
+		panic(err)
+	} else if err = ctloglists.LoadAcceptedRoots(); err != nil {
+		panic(err)
+	}
+
+	var roots []root
+	for _, arg := range flag.Args() {
+		r, err := readRoots(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		roots = append(roots, r...)
+	}
+
+	var logLists []*loglist3.LogList
+	for _, ua := range userAgents {
+		logLists = append(logLists, ctloglists.LogListByName(ua.listName))
+	}
+	var allLogLists []*loglist3.LogList
+	for _, name := range ctloglists.LogListNames {
+		allLogLists = append(allLogLists, ctloglists.LogListByName(name))
+	}
+	intervals := temporalIntervals(allLogLists)
+
+	var output []coverageJSON
+	for _, r := range roots {
+		output = append(output, coverage(r, ctloglists.LogsAcceptingRoot(r.Fingerprint), logLists, intervals))
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(output)
+	default:
+		printText(output)
+	}
+}
+
+// readRoots returns the root specified directly as a hex SHA-256 fingerprint, or else the roots in the specified file.
+func readRoots(arg string) ([]root, error) {
+	if decoded, err := hex.DecodeString(strings.ReplaceAll(arg, ":", "")); err == nil && len(decoded) == sha256.Size {
+		var r root
+		copy(r.Fingerprint[:], decoded)
+		return []root{r}, nil
+	}
+
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return nil, err
+	}
+	var ders [][]byte
+	for rest := data; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		} else if block.Type == "CERTIFICATE" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+	if len(ders) == 0 {
+		ders = [][]byte{data}
+	}
+
+	var roots []root
+	for _, der := range ders {
+		cert, err := x509.ParseCertificate(der)
+		if x509.IsFatal(err) {
+			return nil, fmt.Errorf("failed to parse certificate from %s: %v", arg, err)
+		}
+		roots = append(roots, root{Fingerprint: sha256.Sum256(der), Subject: cert.Subject.String()})
+	}
+	return roots, nil
+}
+
+// temporalIntervals returns the intersection of each log's temporal intervals in logLists, for logs that have one in any of them.
+// Unlike ctloglists.TemporalIntervalMap, the result doesn't depend on the order of logLists.
+func temporalIntervals(logLists []*loglist3.LogList) map[[sha256.Size]byte]*loglist3.TemporalInterval {
+	intervals := make(map[[sha256.Size]byte]*loglist3.TemporalInterval)
+	add := func(logID []byte, ti *loglist3.TemporalInterval) {
+		if ti == nil {
+			return
+		}
+		id := [sha256.Size]byte(logID)
+		if interval, ok := intervals[id]; !ok {
+			intervals[id] = &loglist3.TemporalInterval{StartInclusive: ti.StartInclusive, EndExclusive: ti.EndExclusive}
+		} else {
+			if ti.StartInclusive.After(interval.StartInclusive) {
+				interval.StartInclusive = ti.StartInclusive
+			}
+			if ti.EndExclusive.Before(interval.EndExclusive) {
+				interval.EndExclusive = ti.EndExclusive
+			}
+		}
+	}
+	for _, logList := range logLists {
+		if logList == nil {
+			continue
+		}
+		for _, operator := range logList.Operators {
+			for _, log := range operator.Logs {
+				if len(log.LogID) == sha256.Size {
+					add(log.LogID, log.TemporalInterval)
+				}
+			}
+			for _, tiledLog := range operator.TiledLogs {
+				if len(tiledLog.LogID) == sha256.Size {
+					add(tiledLog.LogID, tiledLog.TemporalInterval)
+				}
+			}
+		}
+	}
+	return intervals
+}
+
+// coverage describes the coverage of r by the logs with IDs acceptingLogIDs in logLists, which holds each user agent's log list.
+// A log without a temporal interval in a user agent's log list is assumed to have the one in intervals, since not every log list includes them.
+func coverage(r root, acceptingLogIDs [][sha256.Size]byte, logLists []*loglist3.LogList, intervals map[[sha256.Size]byte]*loglist3.TemporalInterval) coverageJSON {
+	accepting := make(map[[sha256.Size]byte]bool)
+	for _, logID := range acceptingLogIDs {
+		accepting[logID] = true
+	}
+
+	cj := coverageJSON{Fingerprint: hex.EncodeToString(r.Fingerprint[:]), Subject: r.Subject}
+	for i, ua := range userAgents {
+		uac := userAgentCoverageJSON{UserAgent: ua.name, LogList: ua.listName, Operators: []string{}, ShardsPerYear: make(map[string]int)}
+		if logList := logLists[i]; logList != nil {
+			for _, operator := range logList.Operators {
+				logs := 0
+				count := func(logID []byte, state *loglist3.LogStates, ti *loglist3.TemporalInterval) {
+					var id [sha256.Size]byte
+					copy(id[:], logID)
+					if status := state.LogStatus(); !accepting[id] || (status != loglist3.UsableLogStatus && status != loglist3.QualifiedLogStatus) {
+						return
+					}
+					logs++
+					if ti == nil {
+						ti = intervals[id]
+					}
+					uac.ShardsPerYear[expiryYear(ti)]++
+				}
+				for _, log := range operator.Logs {
+					count(log.LogID, log.State, log.TemporalInterval)
+				}
+				for _, tiledLog := range operator.TiledLogs {
+					count(tiledLog.LogID, tiledLog.State, tiledLog.TemporalInterval)
+				}
+				if logs > 0 {
+					uac.Logs += logs
+					uac.Operators = append(uac.Operators, operator.Name)
+				}
+			}
+		}
+		sort.Strings(uac.Operators)
+		cj.UserAgents = append(cj.UserAgents, uac)
+	}
+	return cj
+}
+
+// expiryYear returns the year in which certificates logged to a temporal shard expire, or "unsharded".
+func expiryYear(ti *loglist3.TemporalInterval) string {
+	if ti == nil {
+		return unsharded
+	}
+	return strconv.Itoa(ti.EndExclusive.Add(-time.Nanosecond).Year())
+}
+
+func printText(output []coverageJSON) {
+	for i, cj := range output {
+		if i > 0 {
+			fmt.Printf("\n")
+		}
+		if cj.Subject != "" {
+			fmt.Printf("%s %s\n", cj.Fingerprint, cj.Subject)
+		} else {
+			fmt.Printf("%s\n", cj.Fingerprint)
+		}
+		for _, uac := range cj.UserAgents {
+			fmt.Printf("  %s (%s): %d Usable/Qualified log(s) from %d operator(s)\n", uac.UserAgent, uac.LogList, uac.Logs, len(uac.Operators))
+			if len(uac.Operators) > 0 {
+				fmt.Printf("    Operators: %s\n", strings.Join(uac.Operators, ", "))
+			}
+			var years []string
+			for year := range uac.ShardsPerYear {
+				years = append(years, year)
+			}
+			sort.Strings(years) // "unsharded" sorts after the years.
+			for _, year := range years {
+				fmt.Printf("    %s: %d\n", year, uac.ShardsPerYear[year])
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/sha256"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+func testLogID(name string) []byte {
+	logID := sha256.Sum256([]byte(name))
+	return logID[:]
+}
+
+func interval(start, end int) *loglist3.TemporalInterval {
+	return &loglist3.TemporalInterval{StartInclusive: time.Date(start, 1, 1, 0, 0, 0, 0, time.UTC), EndExclusive: time.Date(end, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+var (
+	usable    = &loglist3.LogStates{Usable: &loglist3.LogState{}}
+	qualified = &loglist3.LogStates{Qualified: &loglist3.LogState{}}
+	retired   = &loglist3.LogStates{Retired: &loglist3.LogState{}}
+)
+
+func TestTemporalIntervals(t *testing.T) {
+	// The lists disagree about shard2025's interval, and only one of them has shard2026's.
+	first := &loglist3.LogList{Operators: []*loglist3.Operator{{Name: "A", Logs: []*loglist3.Log{
+		{LogID: testLogID("shard2025"), TemporalInterval: interval(2024, 2026)},
+		{LogID: testLogID("unsharded")},
+	}}}}
+	second := &loglist3.LogList{Operators: []*loglist3.Operator{{Name: "A", Logs: []*loglist3.Log{
+		{LogID: testLogID("shard2025"), TemporalInterval: interval(2025, 2027)},
+	}, TiledLogs: []*loglist3.TiledLog{
+		{LogID: testLogID("shard2026"), TemporalInterval: interval(2026, 2027)},
+	}}}}
+
+	intervals := temporalIntervals([]*loglist3.LogList{first, second, nil})
+	if len(intervals) != 2 {
+		t.Fatalf("temporalIntervals returned %d intervals, want 2", len(intervals))
+	}
+	if got := intervals[[sha256.Size]byte(testLogID("shard2025"))]; *got != *interval(2025, 2026) {
+		t.Errorf("shard2025's interval = %v, want the intersection 2025-2026", got)
+	}
+	if got := intervals[[sha256.Size]byte(testLogID("shard2026"))]; *got != *interval(2026, 2027) {
+		t.Errorf("shard2026's interval = %v, want 2026-2027", got)
+	}
+
+	// The result doesn't depend on the order of the lists, and the lists' own intervals are unchanged.
+	reversed := temporalIntervals([]*loglist3.LogList{second, first})
+	if !maps.EqualFunc(intervals, reversed, func(a, b *loglist3.TemporalInterval) bool { return *a == *b }) {
+		t.Errorf("temporalIntervals depends on the order of the log lists")
+	}
+	if *first.Operators[0].Logs[0].TemporalInterval != *interval(2024, 2026) || *second.Operators[0].Logs[0].TemporalInterval != *interval(2025, 2027) {
+		t.Errorf("temporalIntervals modified the log lists' intervals")
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	chrome := &loglist3.LogList{Operators: []*loglist3.Operator{
+		{Name: "Beta", Logs: []*loglist3.Log{
+			// Chrome's list has no interval for this shard; Apple's does.
+			{LogID: testLogID("beta2025"), State: usable},
+			{LogID: testLogID("beta2026"), State: qualified, TemporalInterval: interval(2026, 2027)},
+			// Not Usable or Qualified.
+			{LogID: testLogID("beta2024"), State: retired, TemporalInterval: interval(2024, 2025)},
+		}},
+		{Name: "Alpha", TiledLogs: []*loglist3.TiledLog{
+			{LogID: testLogID("alpha"), State: usable},
+			// Doesn't accept the root.
+			{LogID: testLogID("alpha2026"), State: usable, TemporalInterval: interval(2026, 2027)},
+		}},
+		// Accepts no roots.
+		{Name: "Gamma", Logs: []*loglist3.Log{{LogID: testLogID("gamma"), State: usable}}},
+	}}
+	apple := &loglist3.LogList{Operators: []*loglist3.Operator{
+		{Name: "Beta", Logs: []*loglist3.Log{{LogID: testLogID("beta2025"), State: usable, TemporalInterval: interval(2025, 2026)}}},
+	}}
+	accepting := [][sha256.Size]byte{[sha256.Size]byte(testLogID("beta2025")), [sha256.Size]byte(testLogID("beta2026")), [sha256.Size]byte(testLogID("beta2024")), [sha256.Size]byte(testLogID("alpha"))}
+	r := root{Fingerprint: sha256.Sum256([]byte("root")), Subject: "CN=Test Root"}
+
+	cj := coverage(r, accepting, []*loglist3.LogList{chrome, apple, nil}, temporalIntervals([]*loglist3.LogList{chrome, apple}))
+	if len(cj.UserAgents) != len(userAgents) {
+		t.Fatalf("coverage returned %d user agents, want %d", len(cj.UserAgents), len(userAgents))
+	}
+	for i, want := range []userAgentCoverageJSON{
+		{UserAgent: "chrome", LogList: "gstatic-all", Logs: 3, Operators: []string{"Alpha", "Beta"}, ShardsPerYear: map[string]int{"2025": 1, "2026": 1, unsharded: 1}},
+		{UserAgent: "apple", LogList: "apple-current", Logs: 1, Operators: []string{"Beta"}, ShardsPerYear: map[string]int{"2025": 1}},
+		{UserAgent: "mozilla", LogList: "mozilla-known", Operators: []string{}, ShardsPerYear: map[string]int{}},
+	} {
+		got := cj.UserAgents[i]
+		if got.UserAgent != want.UserAgent || got.LogList != want.LogList || got.Logs != want.Logs || !slices.Equal(got.Operators, want.Operators) || !maps.Equal(got.ShardsPerYear, want.ShardsPerYear) {
+			t.Errorf("coverage for %s = %+v, want %+v", want.UserAgent, got, want)
+		}
+	}
+}
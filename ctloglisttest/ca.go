@@ -0,0 +1,102 @@
+package ctloglisttest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	stdx509 "crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"time"
+
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// CA is a certificate authority whose private key is held locally, for issuing the certificates and precertificates that tests submit to logs.
+type CA struct {
+	Certificate *x509.Certificate
+	Signer      *ecdsa.PrivateKey
+}
+
+// NewRootCA generates an ephemeral ECDSA P-256 key pair and returns a CA with a self-signed root certificate.
+func NewRootCA(commonName string) (*CA, error) {
+	return newCA(commonName, nil)
+}
+
+// NewIntermediateCA generates an ephemeral ECDSA P-256 key pair and returns a CA with an intermediate certificate issued by ca.
+func (ca *CA) NewIntermediateCA(commonName string) (*CA, error) {
+	return newCA(commonName, ca)
+}
+
+func newCA(commonName string, issuer *CA) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &stdx509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              stdx509.KeyUsageCertSign,
+	}
+	ca := &CA{Signer: key}
+	if issuer == nil {
+		// Self-signed.
+		issuer = ca
+	}
+	if ca.Certificate, err = issuer.issue(template, &key.PublicKey); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// IssueCertificate issues a TLS server certificate for dnsName, with a newly generated key.
+func (ca *CA) IssueCertificate(dnsName string) (*x509.Certificate, error) {
+	return ca.issueLeaf(dnsName, nil)
+}
+
+// IssuePrecertificate issues a precertificate (i.e. one carrying the CT poison extension) for dnsName, with a newly generated key.
+func (ca *CA) IssuePrecertificate(dnsName string) (*x509.Certificate, error) {
+	poison, err := asn1.Marshal(asn1.NullRawValue)
+	if err != nil {
+		return nil, err
+	}
+	return ca.issueLeaf(dnsName, []pkix.Extension{{Id: asn1.ObjectIdentifier(x509.OIDExtensionCTPoison), Critical: true, Value: poison}})
+}
+
+func (ca *CA) issueLeaf(dnsName string, extraExtensions []pkix.Extension) (*x509.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return ca.issue(&stdx509.Certificate{
+		Subject:         pkix.Name{CommonName: dnsName},
+		DNSNames:        []string{dnsName},
+		ExtKeyUsage:     []stdx509.ExtKeyUsage{stdx509.ExtKeyUsageServerAuth},
+		ExtraExtensions: extraExtensions,
+	}, &key.PublicKey)
+}
+
+// issue signs template, which must not have been issued by ca before, valid from an hour ago for a day.
+func (ca *CA) issue(template *stdx509.Certificate, publicKey *ecdsa.PublicKey) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = template.NotBefore.Add(24 * time.Hour)
+
+	parent := template
+	if ca.Certificate != nil {
+		if parent, err = stdx509.ParseCertificate(ca.Certificate.Raw); err != nil {
+			return nil, err
+		}
+	}
+	der, err := stdx509.CreateCertificate(rand.Reader, template, parent, publicKey, ca.Signer)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
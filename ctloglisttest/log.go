@@ -0,0 +1,168 @@
+// Package ctloglisttest provides CT logs backed by locally held private keys, for use in tests that need certificates carrying valid SCTs without contacting real logs.
+package ctloglisttest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/crtsh/ctloglists"
+
+	ctgo "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+// Log is a CT log whose private key is held locally, so that it can sign SCTs.
+type Log struct {
+	// Signer is the log's private key.
+	Signer crypto.Signer
+	// LogID is the SHA-256 hash of the log's DER-encoded public key.
+	LogID [sha256.Size]byte
+	// Entry describes the log as it would appear in a log list.
+	Entry *loglist3.Log
+}
+
+// NewLog generates an ephemeral ECDSA P-256 key pair and returns a Usable log that signs with it.
+func NewLog(description string) (*Log, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogFromSigner(privateKey, description)
+}
+
+// NewLogFromSigner returns a Usable log that signs with signer.
+// This is how a test signs as one of the Chrome log mimics, given the mimic's private key: the resulting log has the same log ID as the corresponding entry in ctloglists.LogMimics.
+func NewLogFromSigner(signer crypto.Signer, description string) (*Log, error) {
+	switch signer.Public().(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", signer.Public())
+	}
+
+	key, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	logID := sha256.Sum256(key)
+
+	return &Log{
+		Signer: signer,
+		LogID:  logID,
+		Entry: &loglist3.Log{
+			Description: description,
+			LogID:       logID[:],
+			Key:         key,
+			URL:         fmt.Sprintf("https://ct.example.com/%x/", logID[:4]),
+			MMD:         86400,
+			State: &loglist3.LogStates{
+				Usable: &loglist3.LogState{Timestamp: time.Unix(0, 0).UTC()},
+			},
+			Type: "test",
+		},
+	}, nil
+}
+
+// LogList returns a log list containing only this log, run by an operator named after the log.
+func (l *Log) LogList() *loglist3.LogList {
+	return &loglist3.LogList{
+		Operators: []*loglist3.Operator{
+			{
+				Name:      l.Entry.Description + " Operator",
+				Email:     []string{"no-reply@example.com"},
+				Logs:      []*loglist3.Log{l.Entry},
+				TiledLogs: []*loglist3.TiledLog{},
+			},
+		},
+	}
+}
+
+// Register adds the log to ctloglists.LogSignatureVerifierMap and, if the log has one, ctloglists.TemporalIntervalMap, so that verification against those maps accepts its SCTs.
+// The returned function restores those maps' previous entries for the log's ID (e.g. those loaded by ctloglists.LoadLogLists for a log mimic), or removes the log from them if there were none.
+func (l *Log) Register() (func(), error) {
+	sv, err := ctgo.NewSignatureVerifier(l.Signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	previousSV, hadSV := ctloglists.LogSignatureVerifierMap[l.LogID]
+	ctloglists.LogSignatureVerifierMap[l.LogID] = sv
+	setTemporalInterval := l.Entry.TemporalInterval != nil
+	previousTI, hadTI := ctloglists.TemporalIntervalMap[l.LogID]
+	if setTemporalInterval {
+		ctloglists.TemporalIntervalMap[l.LogID] = l.Entry.TemporalInterval
+	}
+	return func() {
+		if hadSV {
+			ctloglists.LogSignatureVerifierMap[l.LogID] = previousSV
+		} else {
+			delete(ctloglists.LogSignatureVerifierMap, l.LogID)
+		}
+		if !setTemporalInterval {
+			return
+		} else if hadTI {
+			ctloglists.TemporalIntervalMap[l.LogID] = previousTI
+		} else {
+			delete(ctloglists.TemporalIntervalMap, l.LogID)
+		}
+	}, nil
+}
+
+// SignX509SCT returns an SCT for the certificate at chain[0], as returned by add-chain.
+func (l *Log) SignX509SCT(chain []*x509.Certificate, timestamp time.Time) (*ctgo.SignedCertificateTimestamp, error) {
+	return l.signSCT(chain, ctgo.X509LogEntryType, timestamp)
+}
+
+// SignPrecertSCT returns an SCT for the precertificate at chain[0], as returned by add-pre-chain.
+// chain[1] must be the precertificate's issuer; if that is a Precertificate Signing Certificate, chain[2] must be its issuer.
+func (l *Log) SignPrecertSCT(chain []*x509.Certificate, timestamp time.Time) (*ctgo.SignedCertificateTimestamp, error) {
+	return l.signSCT(chain, ctgo.PrecertLogEntryType, timestamp)
+}
+
+func (l *Log) signSCT(chain []*x509.Certificate, entryType ctgo.LogEntryType, timestamp time.Time) (*ctgo.SignedCertificateTimestamp, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("empty chain")
+	}
+	ts := uint64(timestamp.UnixMilli())
+	leaf, err := ctgo.MerkleTreeLeafFromChain(chain, entryType, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	sct := ctgo.SignedCertificateTimestamp{
+		SCTVersion: ctgo.V1,
+		LogID:      ctgo.LogID{KeyID: l.LogID},
+		Timestamp:  ts,
+		Extensions: ctgo.CTExtensions{},
+	}
+	data, err := ctgo.SerializeSCTSignatureInput(sct, ctgo.LogEntry{Leaf: *leaf})
+	if err != nil {
+		return nil, err
+	}
+	if sct.Signature, err = l.sign(data); err != nil {
+		return nil, err
+	}
+	return &sct, nil
+}
+
+func (l *Log) sign(data []byte) (ctgo.DigitallySigned, error) {
+	var sig ctgo.DigitallySigned
+	sig.Algorithm.Hash = tls.SHA256
+	switch l.Signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		sig.Algorithm.Signature = tls.ECDSA
+	case *rsa.PublicKey:
+		sig.Algorithm.Signature = tls.RSA
+	}
+
+	digest := sha256.Sum256(data)
+	var err error
+	sig.Signature, err = l.Signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	return sig, err
+}
@@ -0,0 +1,162 @@
+package ctloglisttest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crtsh/ctloglists"
+
+	ctgo "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+// testChains holds a root, a certificate and a precertificate issued by it, and a root that didn't issue them.
+type testChains struct {
+	root, otherRoot, cert, precert *x509.Certificate
+}
+
+// newTestChains generates testChains.
+func newTestChains(t *testing.T) testChains {
+	t.Helper()
+	root, err := NewRootCA("Test Root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRoot, err := NewRootCA("Other Test Root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := root.IssueCertificate("test.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	precert, err := root.IssuePrecertificate("test.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testChains{root: root.Certificate, otherRoot: otherRoot.Certificate, cert: cert, precert: precert}
+}
+
+func (c testChains) rootPool() *x509util.PEMCertPool {
+	pool := x509util.NewPEMCertPool()
+	pool.AddCert(c.root)
+	return pool
+}
+
+// verifySCT checks that sct is signed by log over the entry for chain.
+func verifySCT(t *testing.T, log *Log, sct *ctgo.SignedCertificateTimestamp, chain []*x509.Certificate, entryType ctgo.LogEntryType) {
+	t.Helper()
+	if sct.LogID.KeyID != log.LogID {
+		t.Errorf("SCT log ID = %x, want %x", sct.LogID.KeyID, log.LogID)
+	}
+	leaf, err := ctgo.MerkleTreeLeafFromChain(chain, entryType, sct.Timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv, err := ctgo.NewSignatureVerifier(log.Signer.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sv.VerifySCTSignature(*sct, ctgo.LogEntry{Leaf: *leaf}); err != nil {
+		t.Errorf("SCT signature doesn't verify: %v", err)
+	}
+}
+
+func TestSignSCTs(t *testing.T) {
+	log, err := NewLog("Test Log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chains := newTestChains(t)
+	timestamp := time.UnixMilli(1700000000000)
+
+	sct, err := log.SignX509SCT([]*x509.Certificate{chains.cert, chains.root}, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sct.Timestamp != uint64(timestamp.UnixMilli()) {
+		t.Errorf("SCT timestamp = %d, want %d", sct.Timestamp, timestamp.UnixMilli())
+	}
+	verifySCT(t, log, sct, []*x509.Certificate{chains.cert, chains.root}, ctgo.X509LogEntryType)
+
+	sct, err = log.SignPrecertSCT([]*x509.Certificate{chains.precert, chains.root}, timestamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifySCT(t, log, sct, []*x509.Certificate{chains.precert, chains.root}, ctgo.PrecertLogEntryType)
+
+	if _, err = log.SignX509SCT(nil, timestamp); err == nil {
+		t.Errorf("SignX509SCT accepted an empty chain")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	log, err := NewLog("Test Log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Entry.TemporalInterval = &loglist3.TemporalInterval{StartInclusive: time.Unix(0, 0), EndExclusive: time.Unix(1, 0)}
+
+	cleanup, err := log.Register()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctloglists.LogSignatureVerifierMap[log.LogID] == nil || ctloglists.TemporalIntervalMap[log.LogID] != log.Entry.TemporalInterval {
+		t.Errorf("Register didn't add the log to the maps")
+	}
+	cleanup()
+	if _, ok := ctloglists.LogSignatureVerifierMap[log.LogID]; ok {
+		t.Errorf("cleanup didn't remove the log from LogSignatureVerifierMap")
+	}
+	if _, ok := ctloglists.TemporalIntervalMap[log.LogID]; ok {
+		t.Errorf("cleanup didn't remove the log from TemporalIntervalMap")
+	}
+}
+
+// TestRegisterRestoresPreviousEntries checks that registering a log with the same log ID as an already loaded log (as when signing with a log mimic's key) and cleaning up leaves the loaded log's entries in place.
+func TestRegisterRestoresPreviousEntries(t *testing.T) {
+	log, err := NewLog("Test Log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedSV, err := ctgo.NewSignatureVerifier(log.Signer.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedTI := &loglist3.TemporalInterval{StartInclusive: time.Unix(0, 0), EndExclusive: time.Unix(1, 0)}
+	ctloglists.LogSignatureVerifierMap[log.LogID] = loadedSV
+	ctloglists.TemporalIntervalMap[log.LogID] = loadedTI
+	defer delete(ctloglists.LogSignatureVerifierMap, log.LogID)
+	defer delete(ctloglists.TemporalIntervalMap, log.LogID)
+
+	// A log without a temporal interval mustn't touch TemporalIntervalMap.
+	cleanup, err := log.Register()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctloglists.LogSignatureVerifierMap[log.LogID] == loadedSV {
+		t.Errorf("Register didn't replace the loaded signature verifier")
+	}
+	cleanup()
+	if ctloglists.LogSignatureVerifierMap[log.LogID] != loadedSV {
+		t.Errorf("cleanup didn't restore the loaded signature verifier")
+	}
+	if ctloglists.TemporalIntervalMap[log.LogID] != loadedTI {
+		t.Errorf("cleanup didn't leave the loaded temporal interval in place")
+	}
+
+	// A log with a temporal interval replaces and then restores the loaded one.
+	log.Entry.TemporalInterval = &loglist3.TemporalInterval{StartInclusive: time.Unix(2, 0), EndExclusive: time.Unix(3, 0)}
+	if cleanup, err = log.Register(); err != nil {
+		t.Fatal(err)
+	}
+	if ctloglists.TemporalIntervalMap[log.LogID] != log.Entry.TemporalInterval {
+		t.Errorf("Register didn't replace the loaded temporal interval")
+	}
+	cleanup()
+	if ctloglists.LogSignatureVerifierMap[log.LogID] != loadedSV || ctloglists.TemporalIntervalMap[log.LogID] != loadedTI {
+		t.Errorf("cleanup didn't restore the loaded entries")
+	}
+}
@@ -0,0 +1,207 @@
+package ctloglisttest
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/crtsh/ctloglists"
+
+	ctgo "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/tls"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+// Server is an in-process fake RFC 6962 CT log implementing get-roots, add-chain, add-pre-chain and get-sth.
+// It is an http.Handler, so it can be wrapped by httptest.NewServer, or started with Start.
+type Server struct {
+	// Log is the log that the server signs SCTs and STHs as.
+	Log *Log
+	// Now returns the time used for SCT and STH timestamps. It defaults to time.Now.
+	Now func() time.Time
+
+	roots      *x509util.PEMCertPool
+	mux        *http.ServeMux
+	mutex      sync.Mutex
+	leafHashes [][sha256.Size]byte
+}
+
+// NewServer returns a fake log, signing with a newly generated key, that accepts the roots in the ctloglists.AcceptedRootsMap entry identified by rootsListHash.
+// LoadAcceptedRoots must have been called first.
+func NewServer(rootsListHash [sha256.Size]byte) (*Server, error) {
+	roots := ctloglists.AcceptedRootsMap[rootsListHash]
+	if roots == nil {
+		return nil, fmt.Errorf("no accepted roots list with hash %x", rootsListHash)
+	}
+	log, err := NewLog(fmt.Sprintf("Fake Log %x", rootsListHash[:4]))
+	if err != nil {
+		return nil, err
+	}
+	return NewServerWithRoots(log, roots), nil
+}
+
+// NewServerWithRoots returns a fake log that signs as log and accepts the roots in roots.
+func NewServerWithRoots(log *Log, roots *x509util.PEMCertPool) *Server {
+	s := &Server{Log: log, Now: time.Now, roots: roots, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /ct/v1/get-roots", s.getRoots)
+	s.mux.HandleFunc("POST /ct/v1/add-chain", s.addChain(ctgo.X509LogEntryType))
+	s.mux.HandleFunc("POST /ct/v1/add-pre-chain", s.addChain(ctgo.PrecertLogEntryType))
+	s.mux.HandleFunc("GET /ct/v1/get-sth", s.getSTH)
+	return s
+}
+
+// Start starts an httptest.Server for the fake log and updates Log.Entry.URL to point at it.
+// The caller should call Close on the returned server when finished.
+func (s *Server) Start() *httptest.Server {
+	ts := httptest.NewServer(s)
+	s.Log.Entry.URL = ts.URL + "/"
+	return ts
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) getRoots(w http.ResponseWriter, r *http.Request) {
+	var response ctgo.GetRootsResponse
+	response.Certificates = []string{}
+	for _, root := range s.roots.RawCertificates() {
+		response.Certificates = append(response.Certificates, base64.StdEncoding.EncodeToString(root.Raw))
+	}
+	writeJSON(w, response)
+}
+
+func (s *Server) addChain(entryType ctgo.LogEntryType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request ctgo.AddChainRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, fmt.Sprintf("failed to decode request: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		chain, err := s.verifyChain(request.Chain)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		isPrecert := chain[0].IsPrecertificate()
+		if isPrecert != (entryType == ctgo.PrecertLogEntryType) {
+			http.Error(w, fmt.Sprintf("precertificate=%t submitted as %s", isPrecert, entryType), http.StatusBadRequest)
+			return
+		}
+
+		timestamp := s.Now()
+		sct, err := s.Log.signSCT(chain, entryType, timestamp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		leaf, err := ctgo.MerkleTreeLeafFromChain(chain, entryType, sct.Timestamp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		leafHash, err := ctgo.LeafHashForLeaf(leaf)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		s.mutex.Lock()
+		s.leafHashes = append(s.leafHashes, leafHash)
+		s.mutex.Unlock()
+
+		signature, err := tls.Marshal(sct.Signature)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, ctgo.AddChainResponse{
+			SCTVersion: sct.SCTVersion,
+			ID:         sct.LogID.KeyID[:],
+			Timestamp:  sct.Timestamp,
+			Signature:  signature,
+		})
+	}
+}
+
+// verifyChain parses rawChain and checks that it leads to one of the accepted roots, returning the verified chain including the root.
+func (s *Server) verifyChain(rawChain [][]byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for i, der := range rawChain {
+		cert, err := x509.ParseCertificate(der)
+		if x509.IsFatal(err) {
+			return nil, fmt.Errorf("failed to parse chain[%d]: %v", i, err)
+		}
+		chain = append(chain, cert)
+	}
+	return ctloglists.VerifyChain(s.roots, chain)
+}
+
+func (s *Server) getSTH(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	leafHashes := append([][sha256.Size]byte{}, s.leafHashes...)
+	s.mutex.Unlock()
+
+	rootHash := merkleTreeHash(leafHashes)
+	sth := ctgo.SignedTreeHead{
+		Version:        ctgo.V1,
+		TreeSize:       uint64(len(leafHashes)),
+		Timestamp:      uint64(s.Now().UnixMilli()),
+		SHA256RootHash: rootHash,
+	}
+	data, err := ctgo.SerializeSTHSignatureInput(sth)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sth.TreeHeadSignature, err = s.Log.sign(data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	signature, err := tls.Marshal(sth.TreeHeadSignature)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, ctgo.GetSTHResponse{
+		TreeSize:          sth.TreeSize,
+		Timestamp:         sth.Timestamp,
+		SHA256RootHash:    rootHash[:],
+		TreeHeadSignature: signature,
+	})
+}
+
+// merkleTreeHash computes the RFC 6962 Merkle Tree Hash over leafHashes.
+func merkleTreeHash(leafHashes [][sha256.Size]byte) ctgo.SHA256Hash {
+	switch len(leafHashes) {
+	case 0:
+		return sha256.Sum256(nil)
+	case 1:
+		return leafHashes[0]
+	}
+	k := 1
+	for k*2 < len(leafHashes) {
+		k *= 2
+	}
+	left := merkleTreeHash(leafHashes[:k])
+	right := merkleTreeHash(leafHashes[k:])
+	return sha256.Sum256(append(append([]byte{1}, left[:]...), right[:]...))
+}
+
+// writeJSON encodes v before writing anything, so that an encoding error can still be reported with an error status.
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
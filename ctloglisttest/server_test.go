@@ -0,0 +1,184 @@
+package ctloglisttest
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ctgo "github.com/google/certificate-transparency-go"
+	"github.com/google/certificate-transparency-go/client"
+	"github.com/google/certificate-transparency-go/jsonclient"
+	"github.com/google/certificate-transparency-go/x509"
+)
+
+func newTestServer(t *testing.T, chains testChains) (*Server, *client.LogClient) {
+	t.Helper()
+	log, err := NewLog("Test Log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServerWithRoots(log, chains.rootPool())
+	server.Now = func() time.Time { return time.UnixMilli(1700000000000) }
+	ts := server.Start()
+	t.Cleanup(ts.Close)
+
+	lc, err := client.New(log.Entry.URL, ts.Client(), jsonclient.Options{PublicKeyDER: log.Entry.Key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, lc
+}
+
+func asn1Chain(chain ...*x509.Certificate) []ctgo.ASN1Cert {
+	var asn1Certs []ctgo.ASN1Cert
+	for _, cert := range chain {
+		asn1Certs = append(asn1Certs, ctgo.ASN1Cert{Data: cert.Raw})
+	}
+	return asn1Certs
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	chains := newTestChains(t)
+	server, lc := newTestServer(t, chains)
+	ctx := context.Background()
+
+	roots, err := lc.GetAcceptedRoots(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(roots) != 1 || string(roots[0].Data) != string(chains.root.Raw) {
+		t.Errorf("get-roots returned %d roots, want only the test root", len(roots))
+	}
+
+	// The root may be omitted from a submitted chain.
+	sct, err := lc.AddChain(ctx, asn1Chain(chains.cert))
+	if err != nil {
+		t.Fatalf("add-chain: %v", err)
+	}
+	verifySCT(t, server.Log, sct, []*x509.Certificate{chains.cert, chains.root}, ctgo.X509LogEntryType)
+	if sct.Timestamp != 1700000000000 {
+		t.Errorf("SCT timestamp = %d, want 1700000000000", sct.Timestamp)
+	}
+
+	sct, err = lc.AddPreChain(ctx, asn1Chain(chains.precert, chains.root))
+	if err != nil {
+		t.Fatalf("add-pre-chain: %v", err)
+	}
+	verifySCT(t, server.Log, sct, []*x509.Certificate{chains.precert, chains.root}, ctgo.PrecertLogEntryType)
+
+	sth, err := lc.GetSTH(ctx)
+	if err != nil {
+		t.Fatalf("get-sth: %v", err)
+	}
+	if err = lc.VerifySTHSignature(*sth); err != nil {
+		t.Errorf("STH signature doesn't verify: %v", err)
+	}
+	if sth.TreeSize != 2 {
+		t.Errorf("STH tree size = %d, want 2", sth.TreeSize)
+	}
+	var leafHashes [][sha256.Size]byte
+	for _, entry := range []struct {
+		chain     []*x509.Certificate
+		entryType ctgo.LogEntryType
+	}{
+		{[]*x509.Certificate{chains.cert, chains.root}, ctgo.X509LogEntryType},
+		{[]*x509.Certificate{chains.precert, chains.root}, ctgo.PrecertLogEntryType},
+	} {
+		leaf, err := ctgo.MerkleTreeLeafFromChain(entry.chain, entry.entryType, 1700000000000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leafHash, err := ctgo.LeafHashForLeaf(leaf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leafHashes = append(leafHashes, leafHash)
+	}
+	if want := hashChildren(leafHashes[0], leafHashes[1]); sth.SHA256RootHash != want {
+		t.Errorf("STH root hash = %x, want %x", sth.SHA256RootHash, want)
+	}
+}
+
+func TestServerRejectsBadChains(t *testing.T) {
+	chains := newTestChains(t)
+	_, lc := newTestServer(t, chains)
+	ctx := context.Background()
+
+	for _, test := range []struct {
+		name   string
+		submit func(context.Context, []ctgo.ASN1Cert) (*ctgo.SignedCertificateTimestamp, error)
+		chain  []ctgo.ASN1Cert
+	}{
+		{"unaccepted root", lc.AddChain, asn1Chain(chains.otherRoot)},
+		{"precertificate via add-chain", lc.AddChain, asn1Chain(chains.precert, chains.root)},
+		{"certificate via add-pre-chain", lc.AddPreChain, asn1Chain(chains.cert, chains.root)},
+		{"unparseable certificate", lc.AddChain, []ctgo.ASN1Cert{{Data: []byte("not a certificate")}}},
+		{"empty chain", lc.AddChain, nil},
+	} {
+		if _, err := test.submit(ctx, test.chain); err == nil {
+			t.Errorf("%s: accepted", test.name)
+		}
+	}
+
+	sth, err := lc.GetSTH(ctx)
+	if err != nil {
+		t.Fatalf("get-sth: %v", err)
+	}
+	if err = lc.VerifySTHSignature(*sth); err != nil {
+		t.Errorf("STH signature doesn't verify: %v", err)
+	}
+	if sth.TreeSize != 0 {
+		t.Errorf("STH tree size = %d after only rejected submissions, want 0", sth.TreeSize)
+	}
+	if want := ctgo.SHA256Hash(sha256.Sum256(nil)); sth.SHA256RootHash != want {
+		t.Errorf("empty tree's root hash = %x, want %x", sth.SHA256RootHash, want)
+	}
+}
+
+func hashChildren(left, right [sha256.Size]byte) [sha256.Size]byte {
+	return sha256.Sum256(append(append([]byte{1}, left[:]...), right[:]...))
+}
+
+// TestMerkleTreeHash checks merkleTreeHash against the RFC 6962 section 2.1 definition, for trees of up to 7 leaves.
+func TestMerkleTreeHash(t *testing.T) {
+	var leaves [][sha256.Size]byte
+	for i := 0; i < 7; i++ {
+		leaves = append(leaves, sha256.Sum256(append([]byte{0}, byte(i))))
+	}
+	l := leaves
+	h := hashChildren
+	for _, test := range []struct {
+		n    int
+		want [sha256.Size]byte
+	}{
+		{0, sha256.Sum256(nil)},
+		{1, l[0]},
+		{2, h(l[0], l[1])},
+		{3, h(h(l[0], l[1]), l[2])},
+		{4, h(h(l[0], l[1]), h(l[2], l[3]))},
+		{5, h(h(h(l[0], l[1]), h(l[2], l[3])), l[4])},
+		{7, h(h(h(l[0], l[1]), h(l[2], l[3])), h(h(l[4], l[5]), l[6]))},
+	} {
+		if got := merkleTreeHash(leaves[:test.n]); got != test.want {
+			t.Errorf("merkleTreeHash(%d leaves) = %x, want %x", test.n, got, test.want)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, map[string]int{"tree_size": 2})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" || w.Body.String() != "{\"tree_size\":2}\n" {
+		t.Errorf("writeJSON wrote %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	// An unencodable value is reported with an error status, having written nothing else.
+	w = httptest.NewRecorder()
+	writeJSON(w, map[string]any{"unencodable": make(chan int)})
+	if w.Code != http.StatusInternalServerError || strings.HasPrefix(w.Body.String(), "{") {
+		t.Errorf("writeJSON of an unencodable value wrote %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,133 @@
+package ctloglists
+
+import (
+	"slices"
+	"strings"
+)
+
+// DiffCategories lists the categories of difference in a ListDiff, for use in DiffFilter.Categories.
+var DiffCategories = []string{"added", "removed", "state", "temporal_interval", "fields", "operators"}
+
+// DiffFilter restricts a ListDiff to the differences of interest. Values are matched case-insensitively, and an empty slice matches everything.
+type DiffFilter struct {
+	// Statuses lists log statuses, as named by LogStatusName (e.g. "Usable"). A log present in both lists matches if its status in either list matches.
+	Statuses []string
+	// Types lists log types (e.g. "test" or "monitoring_only"). "prod" matches logs without a type.
+	Types []string
+	// Operators lists operator names. A log matches if its operator in either list matches, and an operator-level difference matches if the operator's name in either list matches.
+	Operators []string
+	// Categories lists DiffCategories.
+	Categories []string
+}
+
+func matchesAny(values []string, value string) bool {
+	return len(values) == 0 || slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+}
+
+// Filter returns the differences in d that match f.
+// Operator-level differences other than log moves have no log status or type, so are excluded by f.Statuses and f.Types.
+func (d ListDiff) Filter(f DiffFilter) ListDiff {
+	// The statuses and operators of logs present in both lists, in the second list.
+	toStatus := make(map[string]string)
+	for _, change := range d.StateChanges {
+		toStatus[string(change.LogID)] = change.To
+	}
+	toOperator := make(map[string]string)
+	for _, move := range d.Operators.LogMoves {
+		toOperator[string(move.LogID)] = move.ToOperator
+	}
+
+	matchesLog := func(dl DiffLog, inBoth bool) bool {
+		logType := dl.Type
+		if logType == "" {
+			logType = "prod"
+		}
+		statusMatches := matchesAny(f.Statuses, dl.Status)
+		operatorMatches := matchesAny(f.Operators, dl.Operator)
+		if inBoth {
+			if status, ok := toStatus[string(dl.LogID)]; ok && len(f.Statuses) > 0 {
+				statusMatches = statusMatches || matchesAny(f.Statuses, status)
+			}
+			if operator, ok := toOperator[string(dl.LogID)]; ok && len(f.Operators) > 0 {
+				operatorMatches = operatorMatches || matchesAny(f.Operators, operator)
+			}
+		}
+		return statusMatches && operatorMatches && matchesAny(f.Types, logType)
+	}
+	matchesOperator := func(names ...string) bool {
+		if len(f.Statuses) > 0 || len(f.Types) > 0 {
+			return false
+		}
+		return slices.ContainsFunc(names, func(name string) bool {
+			return matchesAny(f.Operators, name)
+		})
+	}
+
+	filtered := ListDiff{Added: []DiffLog{}, Removed: []DiffLog{}, StateChanges: []StateChange{}, TemporalIntervalChanges: []TemporalIntervalChange{}, FieldChanges: []FieldChange{}}
+	filtered.Operators = OperatorDiff{Added: []DiffOperator{}, Removed: []DiffOperator{}, Renamed: []OperatorRename{}, EmailChanges: []OperatorEmailChange{}, LogMoves: []LogMove{}}
+	if matchesAny(f.Categories, "added") {
+		for _, dl := range d.Added {
+			if matchesLog(dl, false) {
+				filtered.Added = append(filtered.Added, dl)
+			}
+		}
+	}
+	if matchesAny(f.Categories, "removed") {
+		for _, dl := range d.Removed {
+			if matchesLog(dl, false) {
+				filtered.Removed = append(filtered.Removed, dl)
+			}
+		}
+	}
+	if matchesAny(f.Categories, "state") {
+		for _, change := range d.StateChanges {
+			if matchesLog(change.DiffLog, true) {
+				filtered.StateChanges = append(filtered.StateChanges, change)
+			}
+		}
+	}
+	if matchesAny(f.Categories, "temporal_interval") {
+		for _, change := range d.TemporalIntervalChanges {
+			if matchesLog(change.DiffLog, true) {
+				filtered.TemporalIntervalChanges = append(filtered.TemporalIntervalChanges, change)
+			}
+		}
+	}
+	if matchesAny(f.Categories, "fields") {
+		for _, change := range d.FieldChanges {
+			if matchesLog(change.DiffLog, true) {
+				filtered.FieldChanges = append(filtered.FieldChanges, change)
+			}
+		}
+	}
+	if matchesAny(f.Categories, "operators") {
+		for _, operator := range d.Operators.Added {
+			if matchesOperator(operator.Name) {
+				filtered.Operators.Added = append(filtered.Operators.Added, operator)
+			}
+		}
+		for _, operator := range d.Operators.Removed {
+			if matchesOperator(operator.Name) {
+				filtered.Operators.Removed = append(filtered.Operators.Removed, operator)
+			}
+		}
+		for _, rename := range d.Operators.Renamed {
+			if matchesOperator(rename.From, rename.To) {
+				filtered.Operators.Renamed = append(filtered.Operators.Renamed, rename)
+			}
+		}
+		for _, change := range d.Operators.EmailChanges {
+			if matchesOperator(change.Operator) {
+				filtered.Operators.EmailChanges = append(filtered.Operators.EmailChanges, change)
+			}
+		}
+		for _, move := range d.Operators.LogMoves {
+			if matchesLog(move.DiffLog, true) {
+				filtered.Operators.LogMoves = append(filtered.Operators.LogMoves, move)
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,117 @@
+package ctloglists
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+func TestDiffFilter(t *testing.T) {
+	testLog := newTestLog("test", testUsable)
+	testLog.Type = "test"
+	a := testLogList(
+		&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("retiring", testUsable), newTestLog("moving", testUsable), testLog}},
+		&loglist3.Operator{Name: "B", Email: []string{"b@example.com"}, Logs: []*loglist3.Log{newTestLog("b", testUsable)}},
+		&loglist3.Operator{Name: "Old", Logs: []*loglist3.Log{newTestLog("old", testRetired)}},
+	)
+	b := testLogList(
+		&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("retiring", testRetired), newTestLog("new", testUsable)}},
+		&loglist3.Operator{Name: "B", Email: []string{"b2@example.com"}, Logs: []*loglist3.Log{newTestLog("b", testUsable), newTestLog("moving", testUsable)}},
+		&loglist3.Operator{Name: "New", Logs: []*loglist3.Log{newTestLog("old", testRetired)}},
+		&loglist3.Operator{Name: "C", Logs: []*loglist3.Log{newTestLog("c", testUsable)}},
+	)
+	diff := Diff(a, b)
+
+	for _, test := range []struct {
+		name   string
+		filter DiffFilter
+		want   []string
+	}{
+		{
+			name: "everything",
+			want: []string{"added new (A, tiled=false)", "added c (C, tiled=false)", "removed test (A, tiled=false)", "state retiring: Usable -> Retired", "added operator C", "renamed operator Old -> New", "email B: +[b2@example.com] -[b@example.com]", "moved moving: A -> B"},
+		},
+		{
+			// Operator-level differences other than log moves have no status.
+			name:   "status",
+			filter: DiffFilter{Statuses: []string{"usable"}},
+			want:   []string{"added new (A, tiled=false)", "added c (C, tiled=false)", "removed test (A, tiled=false)", "state retiring: Usable -> Retired", "moved moving: A -> B"},
+		},
+		{
+			// A state change matches its status in either list.
+			name:   "status in the second list",
+			filter: DiffFilter{Statuses: []string{"Retired"}},
+			want:   []string{"state retiring: Usable -> Retired"},
+		},
+		{
+			name:   "type",
+			filter: DiffFilter{Types: []string{"test"}},
+			want:   []string{"removed test (A, tiled=false)"},
+		},
+		{
+			// "prod" matches logs without a type.
+			name:   "prod type",
+			filter: DiffFilter{Types: []string{"PROD"}},
+			want:   []string{"added new (A, tiled=false)", "added c (C, tiled=false)", "state retiring: Usable -> Retired", "moved moving: A -> B"},
+		},
+		{
+			// A log move matches its operator in either list.
+			name:   "operator",
+			filter: DiffFilter{Operators: []string{"b"}},
+			want:   []string{"email B: +[b2@example.com] -[b@example.com]", "moved moving: A -> B"},
+		},
+		{
+			name:   "added operator",
+			filter: DiffFilter{Operators: []string{"C"}},
+			want:   []string{"added c (C, tiled=false)", "added operator C"},
+		},
+		{
+			// A rename matches both of the operator's names.
+			name:   "renamed operator's old name",
+			filter: DiffFilter{Operators: []string{"Old"}},
+			want:   []string{"renamed operator Old -> New"},
+		},
+		{
+			name:   "renamed operator's new name",
+			filter: DiffFilter{Operators: []string{"New"}},
+			want:   []string{"renamed operator Old -> New"},
+		},
+		{
+			name:   "unknown operator",
+			filter: DiffFilter{Operators: []string{"Z"}},
+		},
+		{
+			name:   "categories",
+			filter: DiffFilter{Categories: []string{"added", "state"}},
+			want:   []string{"added new (A, tiled=false)", "added c (C, tiled=false)", "state retiring: Usable -> Retired"},
+		},
+		{
+			name:   "operators category",
+			filter: DiffFilter{Categories: []string{"operators"}},
+			want:   []string{"added operator C", "renamed operator Old -> New", "email B: +[b2@example.com] -[b@example.com]", "moved moving: A -> B"},
+		},
+		{
+			name:   "status, operator and categories",
+			filter: DiffFilter{Statuses: []string{"Usable"}, Operators: []string{"A"}, Categories: []string{"added", "removed"}},
+			want:   []string{"added new (A, tiled=false)", "removed test (A, tiled=false)"},
+		},
+		{
+			name:   "type and operators category",
+			filter: DiffFilter{Types: []string{"prod"}, Categories: []string{"operators"}},
+			want:   []string{"moved moving: A -> B"},
+		},
+		{
+			name:   "status and type",
+			filter: DiffFilter{Statuses: []string{"retired"}, Types: []string{"test"}},
+		},
+	} {
+		filtered := diff.Filter(test.filter)
+		if got := describeDiff(filtered); !slices.Equal(got, test.want) {
+			t.Errorf("%s: Filter = %q, want %q", test.name, got, test.want)
+		}
+		if filtered.Empty() != (len(test.want) == 0) {
+			t.Errorf("%s: Empty() = %v", test.name, filtered.Empty())
+		}
+	}
+}
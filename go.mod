@@ -10,5 +10,6 @@ require (
 require (
 	github.com/go-logr/logr v1.4.4 // indirect
 	golang.org/x/crypto v0.54.0 // indirect
+	google.golang.org/protobuf v1.36.11 // indirect
 	k8s.io/klog/v2 v2.140.0 // indirect
 )
@@ -0,0 +1,205 @@
+// Package loglistsource loads log lists from the bundled log lists, this repository's git history, HTTP(S) URLs or local files.
+// It also reads other files and directories (e.g. the Accepted Roots) from this repository's git history.
+package loglistsource
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/crtsh/ctloglists"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+const httpTimeout = 30 * time.Second
+
+// Load loads the log list identified by arg, which is one of:
+//   - the name of a bundled log list (see ctloglists.LogListNames), which requires ctloglists.LoadLogLists;
+//   - "<name>@<rev>", the bundled log list as of git revision rev of this repository;
+//   - an http:// or https:// URL;
+//   - the path of a local file.
+func Load(arg string) (*loglist3.LogList, error) {
+	data, err := Read(arg)
+	if err != nil {
+		return nil, err
+	} else if data == nil {
+		return ctloglists.LogListByName(arg), nil
+	}
+	logList, err := loglist3.NewFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", arg, err)
+	}
+	return logList, nil
+}
+
+// Read returns the JSON of the log list identified by arg (see Load), or nil if arg names a bundled log list.
+func Read(arg string) ([]byte, error) {
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		return fetch(arg)
+	} else if ctloglists.LogListFilename(arg) != "" {
+		return nil, nil
+	} else if name, rev, ok := strings.Cut(arg, "@"); ok && ctloglists.LogListFilename(name) != "" {
+		return ReadFileAtRevision(rev, ctloglists.LogListFilename(name))
+	}
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a log list name, <name>@<rev>, URL or readable file: %v", arg, err)
+	}
+	return data, nil
+}
+
+// IsLogMimics returns true if arg identifies the bundled log mimics list, at any revision.
+func IsLogMimics(arg string) bool {
+	name, _, _ := strings.Cut(arg, "@")
+	return name == "log-mimics"
+}
+
+// RepositoryRoot returns the root directory of the git repository containing the current directory.
+func RepositoryRoot() (string, error) {
+	topLevel, err := git("", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(topLevel)), nil
+}
+
+// ResolveRevision returns the commit hash of git revision rev of this repository.
+func ResolveRevision(rev string) (string, error) {
+	root, err := RepositoryRoot()
+	if err != nil {
+		return "", err
+	}
+	hash, err := git(root, "rev-parse", "--verify", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(hash)), nil
+}
+
+// ReadFileAtRevision returns the contents of the file at path (relative to the root of this repository) as of git revision rev.
+// The returned error wraps fs.ErrNotExist if rev is valid but the file didn't exist as of rev.
+func ReadFileAtRevision(rev, path string) ([]byte, error) {
+	root, err := RepositoryRoot()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = ResolveRevision(rev); err != nil {
+		return nil, err
+	} else if _, err = git(root, "cat-file", "-e", rev+":"+path); err != nil {
+		return nil, fmt.Errorf("%s:%s: %w", rev, path, fs.ErrNotExist)
+	}
+	return git(root, "show", rev+":"+path)
+}
+
+// LoadAtRevision loads the named bundled log list as of git revision rev, excluding log mimics unless it is the log mimics list. A log list that didn't exist as of rev is treated as empty.
+func LoadAtRevision(rev, name string) (*loglist3.LogList, error) {
+	data, err := ReadFileAtRevision(rev, ctloglists.LogListFilename(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return &loglist3.LogList{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	logList, err := loglist3.NewFromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s@%s: %v", name, rev, err)
+	}
+	if !IsLogMimics(name) {
+		logList = ctloglists.WithoutLogMimics(logList)
+	}
+	return logList, nil
+}
+
+// ExtractDirectoryAtRevision extracts the directory at path (relative to the root of this repository) as of git revision rev into a new temporary directory, which the caller must remove. The directory's contents are beneath path in the temporary directory.
+// The returned error wraps fs.ErrNotExist if rev is valid but the directory didn't exist as of rev.
+func ExtractDirectoryAtRevision(rev, path string) (string, error) {
+	root, err := RepositoryRoot()
+	if err != nil {
+		return "", err
+	}
+	if _, err = ResolveRevision(rev); err != nil {
+		return "", err
+	} else if _, err = git(root, "cat-file", "-e", rev+":"+path); err != nil {
+		return "", fmt.Errorf("%s:%s: %w", rev, path, fs.ErrNotExist)
+	}
+	archive, err := git(root, "archive", "--format=tar", rev, path)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "ctloglists")
+	if err != nil {
+		return "", err
+	}
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		if hdr.Typeflag != tar.TypeReg || !fs.ValidPath(hdr.Name) {
+			continue
+		}
+		filename := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if err = os.MkdirAll(filepath.Dir(filename), 0755); err == nil {
+			var data []byte
+			if data, err = io.ReadAll(tr); err == nil {
+				err = os.WriteFile(filename, data, 0644)
+			}
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// Git runs git with args in the root of this repository, returning its standard output.
+func Git(args ...string) ([]byte, error) {
+	root, err := RepositoryRoot()
+	if err != nil {
+		return nil, err
+	}
+	return git(root, args...)
+}
+
+// git runs git with args in dir (or the current directory, if dir is empty), returning its standard output.
+func git(dir string, args ...string) ([]byte, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func fetch(url string) ([]byte, error) {
+	client := http.Client{Timeout: httpTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,92 @@
+package ctloglists
+
+import (
+	"slices"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// ChangeKind classifies a change between two versions of a log list.
+type ChangeKind string
+
+const (
+	ChangeCosmetic        ChangeKind = "cosmetic"         // The log lists are semantically equivalent.
+	ChangeTimestamp       ChangeKind = "timestamp"        // The version or log_list_timestamp changed.
+	ChangeStateTransition ChangeKind = "state_transition" // A log's state, state timestamp or final tree head changed.
+	ChangeLogAdded        ChangeKind = "log_added"
+	ChangeLogRemoved      ChangeKind = "log_removed"
+	ChangeKey             ChangeKind = "key_change" // A log at an unchanged URL, run by the same operator, has a new key.
+	ChangeOther           ChangeKind = "other"      // Any other change to a log or operator.
+)
+
+// Substantive returns true if the kind of change matters to users of the log list.
+func (k ChangeKind) Substantive() bool {
+	return k != ChangeCosmetic && k != ChangeTimestamp
+}
+
+// KeyChange is a log whose URL and operator are unchanged but whose key (and therefore log ID) differs between two versions of a log list.
+type KeyChange struct {
+	Old DiffLog `json:"old"`
+	New DiffLog `json:"new"`
+}
+
+// ListChanges describes the changes between two versions of a log list.
+type ListChanges struct {
+	Kinds []ChangeKind `json:"kinds"` // In the order in which they are declared.
+	ListDiff
+	KeyChanges []KeyChange `json:"key_changes"` // Removed and added logs paired by URL and operator, which are excluded from ListDiff.Added and ListDiff.Removed.
+}
+
+// Substantive returns true if any of the changes are substantive.
+func (c ListChanges) Substantive() bool {
+	return slices.ContainsFunc(c.Kinds, ChangeKind.Substantive)
+}
+
+// ClassifyChanges compares two versions of a log list, classifying the changes. If there are no changes other than to parts of the JSON that loglist3 ignores or to formatting, the only kind is ChangeCosmetic.
+func ClassifyChanges(oldList, newList *loglist3.LogList) ListChanges {
+	changes := ListChanges{ListDiff: Diff(oldList, newList), KeyChanges: []KeyChange{}}
+	kinds := make(map[ChangeKind]bool)
+
+	if oldList.Version != newList.Version || !oldList.LogListTimestamp.Equal(newList.LogListTimestamp) {
+		kinds[ChangeTimestamp] = true
+	}
+
+	// Pair removed and added logs with the same URL and operator as key changes. Logs without URLs (as in Mozilla's list) are never paired.
+	var added []DiffLog
+	for _, dl := range changes.Added {
+		if i := slices.IndexFunc(changes.Removed, func(r DiffLog) bool {
+			return dl.URL != "" && r.URL == dl.URL && r.Tiled == dl.Tiled && r.Operator == dl.Operator
+		}); i >= 0 {
+			changes.KeyChanges = append(changes.KeyChanges, KeyChange{Old: changes.Removed[i], New: dl})
+			changes.Removed = slices.Delete(changes.Removed, i, i+1)
+		} else {
+			added = append(added, dl)
+		}
+	}
+	changes.Added = append([]DiffLog{}, added...)
+	kinds[ChangeKey] = len(changes.KeyChanges) > 0
+	kinds[ChangeLogAdded] = len(changes.Added) > 0
+	kinds[ChangeLogRemoved] = len(changes.Removed) > 0
+
+	kinds[ChangeStateTransition] = len(changes.StateChanges) > 0
+	for _, change := range changes.FieldChanges {
+		if change.Field == "state_timestamp" || change.Field == "final_tree_head" {
+			kinds[ChangeStateTransition] = true
+		} else {
+			kinds[ChangeOther] = true
+		}
+	}
+	if len(changes.TemporalIntervalChanges) > 0 || !changes.Operators.Empty() {
+		kinds[ChangeOther] = true
+	}
+
+	for _, kind := range []ChangeKind{ChangeTimestamp, ChangeStateTransition, ChangeLogAdded, ChangeLogRemoved, ChangeKey, ChangeOther} {
+		if kinds[kind] {
+			changes.Kinds = append(changes.Kinds, kind)
+		}
+	}
+	if len(changes.Kinds) == 0 {
+		changes.Kinds = []ChangeKind{ChangeCosmetic}
+	}
+	return changes
+}
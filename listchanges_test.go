@@ -0,0 +1,146 @@
+package ctloglists
+
+import (
+	"crypto/sha256"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+func TestClassifyChangesKeyChanges(t *testing.T) {
+	newLog := func(key, url string) *loglist3.Log {
+		logID := sha256.Sum256([]byte(key))
+		return &loglist3.Log{Key: []byte(key), LogID: logID[:], URL: url}
+	}
+	oldList := &loglist3.LogList{Operators: []*loglist3.Operator{
+		{Name: "A", Logs: []*loglist3.Log{newLog("a1", "https://a.example/1/"), newLog("a2", "https://a.example/2/"), newLog("m1", "")}},
+	}}
+	newList := &loglist3.LogList{Operators: []*loglist3.Operator{
+		// Same URL and operator: a key change.
+		{Name: "A", Logs: []*loglist3.Log{newLog("a1'", "https://a.example/1/"), newLog("m2", "")}},
+		// Same URL, different operator: not a key change.
+		{Name: "B", Logs: []*loglist3.Log{newLog("b2", "https://a.example/2/")}},
+	}}
+
+	changes := ClassifyChanges(oldList, newList)
+	if len(changes.KeyChanges) != 1 || changes.KeyChanges[0].Old.URL != "https://a.example/1/" || string(changes.KeyChanges[0].New.LogID) != string(newLog("a1'", "").LogID) {
+		t.Errorf("KeyChanges = %+v, want only https://a.example/1/", changes.KeyChanges)
+	}
+	// Logs without URLs (as in Mozilla's list) are never paired.
+	if len(changes.Added) != 2 || len(changes.Removed) != 2 {
+		t.Errorf("%d logs added and %d removed, want 2 and 2", len(changes.Added), len(changes.Removed))
+	}
+	if !slices.Contains(changes.Kinds, ChangeKey) || !slices.Contains(changes.Kinds, ChangeLogAdded) || !slices.Contains(changes.Kinds, ChangeLogRemoved) {
+		t.Errorf("Kinds = %v, want key_change, log_added and log_removed", changes.Kinds)
+	}
+}
+
+func TestClassifyChangesKinds(t *testing.T) {
+	readOnly := func(treeSize int64) *loglist3.LogStates {
+		return &loglist3.LogStates{ReadOnly: &loglist3.ReadOnlyLogState{LogState: loglist3.LogState{Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}, FinalTreeHead: loglist3.TreeHead{TreeSize: treeSize}}}
+	}
+	// newList returns a log list whose logs can be changed by modify.
+	newList := func(modify func(ll *loglist3.LogList, operator *loglist3.Operator)) *loglist3.LogList {
+		operator := &loglist3.Operator{Name: "A", Email: []string{"a@example.com"}, Logs: []*loglist3.Log{newTestLog("usable", testUsable), newTestLog("readonly", readOnly(100))}}
+		ll := testLogList(operator)
+		ll.Version = "1"
+		ll.LogListTimestamp = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		if modify != nil {
+			modify(ll, operator)
+		}
+		return ll
+	}
+	for _, test := range []struct {
+		name   string
+		modify func(ll *loglist3.LogList, operator *loglist3.Operator)
+		want   []ChangeKind
+	}{
+		{name: "unchanged", want: []ChangeKind{ChangeCosmetic}},
+		{
+			name:   "version",
+			modify: func(ll *loglist3.LogList, _ *loglist3.Operator) { ll.Version = "2" },
+			want:   []ChangeKind{ChangeTimestamp},
+		},
+		{
+			name: "log_list_timestamp",
+			modify: func(ll *loglist3.LogList, _ *loglist3.Operator) {
+				ll.LogListTimestamp = ll.LogListTimestamp.Add(time.Hour)
+			},
+			want: []ChangeKind{ChangeTimestamp},
+		},
+		{
+			name:   "state",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) { operator.Logs[0].State = testRetired },
+			want:   []ChangeKind{ChangeStateTransition},
+		},
+		{
+			name:   "state timestamp",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) { operator.Logs[0].State = testUsable2 },
+			want:   []ChangeKind{ChangeStateTransition},
+		},
+		{
+			name:   "final tree head",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) { operator.Logs[1].State = readOnly(101) },
+			want:   []ChangeKind{ChangeStateTransition},
+		},
+		{
+			name: "log added",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) {
+				operator.Logs = append(operator.Logs, newTestLog("new", testUsable))
+			},
+			want: []ChangeKind{ChangeLogAdded},
+		},
+		{
+			name:   "log removed",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) { operator.Logs = operator.Logs[:1] },
+			want:   []ChangeKind{ChangeLogRemoved},
+		},
+		{
+			name:   "description",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) { operator.Logs[0].Description = "renamed" },
+			want:   []ChangeKind{ChangeOther},
+		},
+		{
+			name: "temporal interval",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) {
+				operator.Logs[0].TemporalInterval = testInterval
+			},
+			want: []ChangeKind{ChangeOther},
+		},
+		{
+			name:   "operator email",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) { operator.Email = []string{"a2@example.com"} },
+			want:   []ChangeKind{ChangeOther},
+		},
+		{
+			// The same log listed as a tiled log rather than an RFC 6962 log.
+			name: "endpoint type",
+			modify: func(_ *loglist3.LogList, operator *loglist3.Operator) {
+				operator.Logs = operator.Logs[1:]
+				tiledLog := newTestTiledLog("usable", testUsable)
+				tiledLog.SubmissionURL = "https://usable.example/"
+				operator.TiledLogs = []*loglist3.TiledLog{tiledLog}
+			},
+			want: []ChangeKind{ChangeOther},
+		},
+		{
+			name: "several",
+			modify: func(ll *loglist3.LogList, operator *loglist3.Operator) {
+				ll.Version = "2"
+				operator.Logs = []*loglist3.Log{newTestLog("new", testUsable), newTestLog("readonly", readOnly(101))}
+			},
+			want: []ChangeKind{ChangeTimestamp, ChangeStateTransition, ChangeLogAdded, ChangeLogRemoved},
+		},
+	} {
+		changes := ClassifyChanges(newList(nil), newList(test.modify))
+		if !slices.Equal(changes.Kinds, test.want) {
+			t.Errorf("%s: Kinds = %v, want %v", test.name, changes.Kinds, test.want)
+		}
+		// Only changes to the version and timestamp are insubstantive.
+		if substantive := !slices.Equal(test.want, []ChangeKind{ChangeCosmetic}) && !slices.Equal(test.want, []ChangeKind{ChangeTimestamp}); changes.Substantive() != substantive {
+			t.Errorf("%s: Substantive() = %v, want %v", test.name, changes.Substantive(), substantive)
+		}
+	}
+}
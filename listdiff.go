@@ -0,0 +1,257 @@
+package ctloglists
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// DiffFields lists the fields that may be compared between logs present in both log lists. They are named after the corresponding log list JSON keys.
+// "endpoint_type" is whether the log is listed as an RFC 6962 log or a tiled (static-ct-api) log, named as in LogEndpointTypeMap; "state" is compared by status, and is reported as a StateChange; "state_timestamp" is the time at which the log entered its current state, and is only compared if the status is unchanged; "final_tree_head" applies to ReadOnly logs; "temporal_interval" is reported as a TemporalIntervalChange; "operator" compares the log lists' operators, as reported by DiffOperators.
+var DiffFields = []string{"endpoint_type", "description", "url", "monitoring_url", "dns", "mmd", "log_type", "previous_operators", "state", "state_timestamp", "final_tree_head", "temporal_interval", "operator"}
+
+// DiffLog identifies a log in a ListDiff.
+type DiffLog struct {
+	LogID       []byte `json:"log_id"`
+	Description string `json:"description"`
+	URL         string `json:"url"` // The submission URL, for a tiled log.
+	Type        string `json:"type,omitempty"`
+	Operator    string `json:"operator"`
+	Status      string `json:"status"` // As named by LogStatusName. For a log present in both lists, this is its status in the first list.
+	Tiled       bool   `json:"tiled"`
+}
+
+// StateChange is a log whose status differs between two log lists.
+type StateChange struct {
+	DiffLog
+	From string `json:"from"` // The log's status in the first list, as named by LogStatusName.
+	To   string `json:"to"`
+}
+
+// TemporalIntervalChange is a log whose temporal interval differs between two log lists.
+type TemporalIntervalChange struct {
+	DiffLog
+	From *loglist3.TemporalInterval `json:"from"`
+	To   *loglist3.TemporalInterval `json:"to"`
+}
+
+// FieldChange is a log whose value of one of the DiffFields (other than "state" and "temporal_interval") differs between two log lists. From and To are the values formatted as strings, and are empty if the field is absent.
+type FieldChange struct {
+	DiffLog
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ListDiff describes the differences between two log lists. Logs are matched by their public keys, whether they are listed as RFC 6962 logs or tiled logs, and are reported in the order in which they appear in the log lists.
+type ListDiff struct {
+	Added                   []DiffLog                `json:"added"`   // Logs present in the second list but not the first.
+	Removed                 []DiffLog                `json:"removed"` // Logs present in the first list but not the second.
+	StateChanges            []StateChange            `json:"state_changes"`
+	TemporalIntervalChanges []TemporalIntervalChange `json:"temporal_interval_changes"`
+	FieldChanges            []FieldChange            `json:"field_changes"`
+	Operators               OperatorDiff             `json:"operators"`
+}
+
+// DiffOptions configures DiffWithOptions.
+type DiffOptions struct {
+	// Fields lists the DiffFields to compare between logs present in both lists. If nil, every field is compared.
+	Fields []string
+}
+
+// logView is the part of a Log or TiledLog that Diff compares.
+type logView struct {
+	DiffLog
+	monitoringURL     string
+	dns               string
+	mmd               int32
+	previousOperators []*loglist3.PreviousOperator
+	state             *loglist3.LogStates
+	temporalInterval  *loglist3.TemporalInterval
+}
+
+func viewLog(operator string, log *loglist3.Log) logView {
+	return logView{
+		DiffLog:           DiffLog{LogID: log.LogID, Description: log.Description, URL: log.URL, Type: log.Type, Operator: operator, Status: LogStatusName(log.State.LogStatus())},
+		dns:               log.DNS,
+		mmd:               log.MMD,
+		previousOperators: log.PreviousOperators,
+		state:             log.State,
+		temporalInterval:  log.TemporalInterval,
+	}
+}
+
+func viewTiledLog(operator string, log *loglist3.TiledLog) logView {
+	return logView{
+		DiffLog:           DiffLog{LogID: log.LogID, Description: log.Description, URL: log.SubmissionURL, Type: log.Type, Operator: operator, Status: LogStatusName(log.State.LogStatus()), Tiled: true},
+		monitoringURL:     log.MonitoringURL,
+		dns:               log.DNS,
+		mmd:               log.MMD,
+		previousOperators: log.PreviousOperators,
+		state:             log.State,
+		temporalInterval:  log.TemporalInterval,
+	}
+}
+
+// Diff compares every field of the logs in log lists a and b. Log mimics are compared like any other log, so callers may wish to use WithoutLogMimics first.
+func Diff(a, b *loglist3.LogList) ListDiff {
+	return DiffWithOptions(a, b, DiffOptions{})
+}
+
+// DiffWithOptions compares log lists a and b, as configured by opts.
+func DiffWithOptions(a, b *loglist3.LogList, opts DiffOptions) ListDiff {
+	diff := ListDiff{Added: []DiffLog{}, Removed: []DiffLog{}, StateChanges: []StateChange{}, TemporalIntervalChanges: []TemporalIntervalChange{}, FieldChanges: []FieldChange{}}
+	compare := func(field string) bool {
+		return opts.Fields == nil || slices.Contains(opts.Fields, field)
+	}
+
+	for _, operator := range a.Operators {
+		for _, logA := range operator.Logs {
+			if viewB, ok := findLogView(b, logA.Key); !ok {
+				diff.Removed = append(diff.Removed, viewLog(operator.Name, logA).DiffLog)
+			} else {
+				diff.compareLogs(viewLog(operator.Name, logA), viewB, compare)
+			}
+		}
+		for _, logA := range operator.TiledLogs {
+			if viewB, ok := findLogView(b, logA.Key); !ok {
+				diff.Removed = append(diff.Removed, viewTiledLog(operator.Name, logA).DiffLog)
+			} else {
+				diff.compareLogs(viewTiledLog(operator.Name, logA), viewB, compare)
+			}
+		}
+	}
+
+	for _, operator := range b.Operators {
+		for _, logB := range operator.Logs {
+			if _, ok := findLogView(a, logB.Key); !ok {
+				diff.Added = append(diff.Added, viewLog(operator.Name, logB).DiffLog)
+			}
+		}
+		for _, logB := range operator.TiledLogs {
+			if _, ok := findLogView(a, logB.Key); !ok {
+				diff.Added = append(diff.Added, viewTiledLog(operator.Name, logB).DiffLog)
+			}
+		}
+	}
+
+	if compare("operator") {
+		diff.Operators = DiffOperators(a, b)
+	} else {
+		diff.Operators = OperatorDiff{Added: []DiffOperator{}, Removed: []DiffOperator{}, Renamed: []OperatorRename{}, EmailChanges: []OperatorEmailChange{}, LogMoves: []LogMove{}}
+	}
+
+	return diff
+}
+
+// findLogView finds the log or tiled log with the given public key in logList. The view's operator is not set.
+func findLogView(logList *loglist3.LogList, key []byte) (logView, bool) {
+	if log := logList.FindLogByKey(key); log != nil {
+		return viewLog("", log), true
+	} else if tiledLog := logList.FindTiledLogByKey(key); tiledLog != nil {
+		return viewTiledLog("", tiledLog), true
+	}
+	return logView{}, false
+}
+
+// Empty returns true if the log lists have no differences.
+func (d ListDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.StateChanges) == 0 && len(d.TemporalIntervalChanges) == 0 && len(d.FieldChanges) == 0 && d.Operators.Empty()
+}
+
+func (d *ListDiff) compareLogs(a, b logView, compare func(string) bool) {
+	addFieldChange := func(field, from, to string) {
+		if compare(field) && from != to {
+			d.FieldChanges = append(d.FieldChanges, FieldChange{DiffLog: a.DiffLog, Field: field, From: from, To: to})
+		}
+	}
+	addFieldChange("endpoint_type", endpointType(a.Tiled), endpointType(b.Tiled))
+	addFieldChange("description", a.Description, b.Description)
+	addFieldChange("url", a.URL, b.URL)
+	addFieldChange("monitoring_url", a.monitoringURL, b.monitoringURL)
+	addFieldChange("dns", a.dns, b.dns)
+	addFieldChange("mmd", strconv.Itoa(int(a.mmd)), strconv.Itoa(int(b.mmd)))
+	addFieldChange("log_type", a.Type, b.Type)
+	addFieldChange("previous_operators", previousOperatorsString(a.previousOperators), previousOperatorsString(b.previousOperators))
+
+	statusA, statusB := a.state.LogStatus(), b.state.LogStatus()
+	if compare("state") && statusA != statusB {
+		d.StateChanges = append(d.StateChanges, StateChange{DiffLog: a.DiffLog, From: LogStatusName(statusA), To: LogStatusName(statusB)})
+	}
+	if statusA == statusB {
+		addFieldChange("state_timestamp", stateTimestampString(a.state), stateTimestampString(b.state))
+	}
+	addFieldChange("final_tree_head", finalTreeHeadString(a.state), finalTreeHeadString(b.state))
+
+	if compare("temporal_interval") && !temporalIntervalsEqual(a.temporalInterval, b.temporalInterval) {
+		d.TemporalIntervalChanges = append(d.TemporalIntervalChanges, TemporalIntervalChange{DiffLog: a.DiffLog, From: a.temporalInterval, To: b.temporalInterval})
+	}
+}
+
+// LogStatusName returns the name of a log status, e.g. "Usable".
+func LogStatusName(status loglist3.LogStatus) string {
+	return strings.Replace(status.String(), "LogStatus", "", -1)
+}
+
+// LogStateTimestamp returns the time at which the log entered its current state, or the zero time if it has no state.
+func LogStateTimestamp(state *loglist3.LogStates) time.Time {
+	if state == nil {
+		return time.Time{}
+	}
+	switch {
+	case state.Pending != nil:
+		return state.Pending.Timestamp
+	case state.Qualified != nil:
+		return state.Qualified.Timestamp
+	case state.Usable != nil:
+		return state.Usable.Timestamp
+	case state.ReadOnly != nil:
+		return state.ReadOnly.Timestamp
+	case state.Retired != nil:
+		return state.Retired.Timestamp
+	case state.Rejected != nil:
+		return state.Rejected.Timestamp
+	}
+	return time.Time{}
+}
+
+func endpointType(tiled bool) string {
+	if tiled {
+		return EndpointTypeStaticCTAPI
+	}
+	return EndpointTypeRFC6962
+}
+
+func stateTimestampString(state *loglist3.LogStates) string {
+	if timestamp := LogStateTimestamp(state); !timestamp.IsZero() {
+		return timestamp.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
+
+func finalTreeHeadString(state *loglist3.LogStates) string {
+	if state == nil || state.ReadOnly == nil {
+		return ""
+	}
+	return fmt.Sprintf("tree_size=%d sha256_root_hash=%s", state.ReadOnly.FinalTreeHead.TreeSize, base64.StdEncoding.EncodeToString(state.ReadOnly.FinalTreeHead.SHA256RootHash))
+}
+
+func previousOperatorsString(previousOperators []*loglist3.PreviousOperator) string {
+	var s []string
+	for _, po := range previousOperators {
+		s = append(s, fmt.Sprintf("%s (until %s)", po.Name, po.EndTime.UTC().Format(time.RFC3339)))
+	}
+	return strings.Join(s, ", ")
+}
+
+func temporalIntervalsEqual(a, b *loglist3.TemporalInterval) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.StartInclusive.Equal(b.StartInclusive) && a.EndExclusive.Equal(b.EndExclusive)
+}
@@ -0,0 +1,196 @@
+package ctloglists
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+var (
+	testUsable   = &loglist3.LogStates{Usable: &loglist3.LogState{Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	testUsable2  = &loglist3.LogStates{Usable: &loglist3.LogState{Timestamp: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}}
+	testRetired  = &loglist3.LogStates{Retired: &loglist3.LogState{Timestamp: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}}
+	testInterval = &loglist3.TemporalInterval{StartInclusive: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), EndExclusive: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}
+)
+
+// newTestLog returns an RFC 6962 log whose key, description and URL are derived from name.
+func newTestLog(name string, state *loglist3.LogStates) *loglist3.Log {
+	logID := sha256.Sum256([]byte(name))
+	return &loglist3.Log{Key: []byte(name), LogID: logID[:], Description: name, URL: "https://" + name + ".example/", MMD: 86400, State: state}
+}
+
+// newTestTiledLog returns a tiled log with the same key, description and (submission) URL as newTestLog(name, state).
+func newTestTiledLog(name string, state *loglist3.LogStates) *loglist3.TiledLog {
+	logID := sha256.Sum256([]byte(name))
+	return &loglist3.TiledLog{Key: []byte(name), LogID: logID[:], Description: name, SubmissionURL: "https://" + name + ".example/", MonitoringURL: "https://" + name + ".example/monitoring/", MMD: 86400, State: state}
+}
+
+func testLogList(operators ...*loglist3.Operator) *loglist3.LogList {
+	return &loglist3.LogList{Operators: operators}
+}
+
+// describeDiff describes each difference in d as a string, for comparison with the expected differences.
+func describeDiff(d ListDiff) []string {
+	var s []string
+	for _, dl := range d.Added {
+		s = append(s, fmt.Sprintf("added %s (%s, tiled=%v)", dl.Description, dl.Operator, dl.Tiled))
+	}
+	for _, dl := range d.Removed {
+		s = append(s, fmt.Sprintf("removed %s (%s, tiled=%v)", dl.Description, dl.Operator, dl.Tiled))
+	}
+	for _, change := range d.StateChanges {
+		s = append(s, fmt.Sprintf("state %s: %s -> %s", change.Description, change.From, change.To))
+	}
+	for _, change := range d.TemporalIntervalChanges {
+		s = append(s, fmt.Sprintf("temporal_interval %s: %v -> %v", change.Description, change.From != nil, change.To != nil))
+	}
+	for _, change := range d.FieldChanges {
+		s = append(s, fmt.Sprintf("%s %s: %s -> %s", change.Field, change.Description, change.From, change.To))
+	}
+	for _, operator := range d.Operators.Added {
+		s = append(s, "added operator "+operator.Name)
+	}
+	for _, operator := range d.Operators.Removed {
+		s = append(s, "removed operator "+operator.Name)
+	}
+	for _, rename := range d.Operators.Renamed {
+		s = append(s, fmt.Sprintf("renamed operator %s -> %s", rename.From, rename.To))
+	}
+	for _, change := range d.Operators.EmailChanges {
+		s = append(s, fmt.Sprintf("email %s: +%v -%v", change.Operator, change.Added, change.Removed))
+	}
+	for _, move := range d.Operators.LogMoves {
+		s = append(s, fmt.Sprintf("moved %s: %s -> %s", move.Description, move.FromOperator, move.ToOperator))
+	}
+	return s
+}
+
+func TestDiff(t *testing.T) {
+	retiredWithInterval := newTestLog("sharded", testRetired)
+	retiredWithInterval.TemporalInterval = testInterval
+	renamed := newTestLog("log", testUsable)
+	renamed.Description = "renamed"
+	for _, test := range []struct {
+		name string
+		a, b *loglist3.LogList
+		want []string
+	}{
+		{
+			name: "identical",
+			a:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable)}, TiledLogs: []*loglist3.TiledLog{newTestTiledLog("tiled", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable)}, TiledLogs: []*loglist3.TiledLog{newTestTiledLog("tiled", testUsable)}}),
+		},
+		{
+			name: "added and removed",
+			a:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("old", testUsable), newTestLog("log", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable)}, TiledLogs: []*loglist3.TiledLog{newTestTiledLog("new", testUsable)}}),
+			want: []string{"added new (A, tiled=true)", "removed old (A, tiled=false)"},
+		},
+		{
+			name: "state change",
+			a:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testRetired)}}),
+			want: []string{"state log: Usable -> Retired"},
+		},
+		{
+			name: "state timestamp change",
+			a:    testLogList(&loglist3.Operator{Name: "A", TiledLogs: []*loglist3.TiledLog{newTestTiledLog("tiled", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A", TiledLogs: []*loglist3.TiledLog{newTestTiledLog("tiled", testUsable2)}}),
+			want: []string{"state_timestamp tiled: 2025-01-01T00:00:00Z -> 2025-02-01T00:00:00Z"},
+		},
+		{
+			name: "state and temporal interval change",
+			a:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("sharded", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{retiredWithInterval}}),
+			want: []string{"state sharded: Usable -> Retired", "temporal_interval sharded: false -> true"},
+		},
+		{
+			name: "field change",
+			a:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{renamed}}),
+			want: []string{"description log: log -> renamed"},
+		},
+		{
+			// A log that moves from RFC 6962 to static-ct-api, keeping its key, is the same log.
+			name: "RFC 6962 log becomes tiled",
+			a:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A", TiledLogs: []*loglist3.TiledLog{newTestTiledLog("log", testUsable)}}),
+			want: []string{"endpoint_type log: rfc6962 -> static-ct-api", "monitoring_url log:  -> https://log.example/monitoring/"},
+		},
+		{
+			name: "tiled log becomes RFC 6962",
+			a:    testLogList(&loglist3.Operator{Name: "A", TiledLogs: []*loglist3.TiledLog{newTestTiledLog("log", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable)}}),
+			want: []string{"endpoint_type log: static-ct-api -> rfc6962", "monitoring_url log: https://log.example/monitoring/ -> "},
+		},
+		{
+			name: "operator renamed",
+			a:    testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable)}}),
+			b:    testLogList(&loglist3.Operator{Name: "A2", Logs: []*loglist3.Log{newTestLog("log", testUsable)}}),
+			want: []string{"renamed operator A -> A2"},
+		},
+	} {
+		diff := Diff(test.a, test.b)
+		if got := describeDiff(diff); !slices.Equal(got, test.want) {
+			t.Errorf("%s: Diff = %q, want %q", test.name, got, test.want)
+		}
+		if diff.Empty() != (len(test.want) == 0) {
+			t.Errorf("%s: Empty() = %v", test.name, diff.Empty())
+		}
+	}
+}
+
+func TestDiffWithOptions(t *testing.T) {
+	changed := newTestLog("log", testRetired)
+	changed.Description = "renamed"
+	changed.TemporalInterval = testInterval
+	a := testLogList(&loglist3.Operator{Name: "A", Logs: []*loglist3.Log{newTestLog("log", testUsable), newTestLog("old", testUsable)}})
+	b := testLogList(&loglist3.Operator{Name: "A2", Logs: []*loglist3.Log{changed}})
+	added := newTestLog("new", testUsable)
+	b.Operators[0].Logs = append(b.Operators[0].Logs, added)
+
+	for _, test := range []struct {
+		fields []string
+		want   []string
+	}{
+		{
+			fields: nil,
+			want:   []string{"added new (A2, tiled=false)", "removed old (A, tiled=false)", "state log: Usable -> Retired", "temporal_interval log: false -> true", "description log: log -> renamed", "renamed operator A -> A2"},
+		},
+		{
+			// Added and removed logs are always reported.
+			fields: []string{},
+			want:   []string{"added new (A2, tiled=false)", "removed old (A, tiled=false)"},
+		},
+		{
+			fields: []string{"state", "temporal_interval"},
+			want:   []string{"added new (A2, tiled=false)", "removed old (A, tiled=false)", "state log: Usable -> Retired", "temporal_interval log: false -> true"},
+		},
+		{
+			fields: []string{"description", "operator"},
+			want:   []string{"added new (A2, tiled=false)", "removed old (A, tiled=false)", "description log: log -> renamed", "renamed operator A -> A2"},
+		},
+		{
+			// The state timestamp is only compared if the status is unchanged.
+			fields: []string{"state_timestamp"},
+			want:   []string{"added new (A2, tiled=false)", "removed old (A, tiled=false)"},
+		},
+	} {
+		if got := describeDiff(DiffWithOptions(a, b, DiffOptions{Fields: test.fields})); !slices.Equal(got, test.want) {
+			t.Errorf("DiffWithOptions(%q) = %q, want %q", test.fields, got, test.want)
+		}
+	}
+
+	// Each field can be selected on its own, and is one of the DiffFields.
+	for _, field := range DiffFields {
+		for _, change := range DiffWithOptions(a, b, DiffOptions{Fields: []string{field}}).FieldChanges {
+			if change.Field != field {
+				t.Errorf("DiffWithOptions(%q) reported a change to %q", field, change.Field)
+			}
+		}
+	}
+}
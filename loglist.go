@@ -4,7 +4,7 @@ import (
 	"crypto/sha256"
 	"embed"
 	"encoding/hex"
-	"fmt"
+	"encoding/pem"
 	"strings"
 	"time"
 
@@ -30,39 +30,152 @@ var LogSignatureVerifierMap map[[sha256.Size]byte]*ctgo.SignatureVerifier
 var TemporalIntervalMap map[[sha256.Size]byte]*loglist3.TemporalInterval
 var AcceptedRootsMap map[[sha256.Size]byte]*x509util.PEMCertPool
 var LogAcceptedRootsMap map[[sha256.Size]byte][sha256.Size]byte
+var LogMimicsMap map[[sha256.Size]byte]*loglist3.Log
+var TiledLogMimicsMap map[[sha256.Size]byte]*loglist3.TiledLog
+var LogEndpointTypeMap map[[sha256.Size]byte]string
+var QuarantinedRootsMap map[[sha256.Size]byte][]QuarantinedRoot
+
+// Types of endpoint from which a log's Accepted Roots are fetched.
+const (
+	EndpointTypeRFC6962     = "rfc6962"
+	EndpointTypeStaticCTAPI = "static-ct-api"
+)
+
+// bundledLogLists describes each bundled log list: the name by which LogListByName selects it, its path relative to the root of this repository, and the variable that LoadLogLists loads it into.
+var bundledLogLists = []struct {
+	name     string
+	filename string
+	logList  **loglist3.LogList
+}{
+	{"gstatic-all", gstaticV3AllLogsListFilename, &GstaticV3All},
+	{"apple-current", appleCurrentLogListFilename, &AppleCurrent},
+	{"crtsh-all", crtshV3AllLogsListFilename, &CrtshV3All},
+	{"crtsh-active", crtshV3ActiveLogsListFilename, &CrtshV3Active},
+	{"mozilla-known", mozillaV3KnownLogsListFilename, &MozillaV3Known},
+	{"bimi-approved", bimiV3ApprovedLogsListFilename, &BimiV3Approved},
+	{"log-mimics", logMimicsListFilename, &LogMimics},
+}
+
+// LogListNames lists the names by which LogListByName selects each bundled log list.
+var LogListNames = func() []string {
+	var names []string
+	for _, bll := range bundledLogLists {
+		names = append(names, bll.name)
+	}
+	return names
+}()
 
 func init() {
 	LogSignatureVerifierMap = make(map[[sha256.Size]byte]*ctgo.SignatureVerifier)
 	TemporalIntervalMap = make(map[[sha256.Size]byte]*loglist3.TemporalInterval)
 	AcceptedRootsMap = make(map[[sha256.Size]byte]*x509util.PEMCertPool)
 	LogAcceptedRootsMap = make(map[[sha256.Size]byte][sha256.Size]byte)
+	LogMimicsMap = make(map[[sha256.Size]byte]*loglist3.Log)
+	TiledLogMimicsMap = make(map[[sha256.Size]byte]*loglist3.TiledLog)
+	LogEndpointTypeMap = make(map[[sha256.Size]byte]string)
+	QuarantinedRootsMap = make(map[[sha256.Size]byte][]QuarantinedRoot)
 }
 
 func LoadLogLists() error {
-	var err error
+	for _, bll := range bundledLogLists {
+		logList, err := loadLogList(bll.filename)
+		if err != nil {
+			return err
+		}
+		*bll.logList = logList
+	}
+	populateLogMimicsMap()
+	return nil
+}
 
-	if GstaticV3All, err = loadLogList(gstaticV3AllLogsListFilename); err == nil {
-		if AppleCurrent, err = loadLogList(appleCurrentLogListFilename); err == nil {
-			if CrtshV3All, err = loadLogList(crtshV3AllLogsListFilename); err == nil {
-				if CrtshV3Active, err = loadLogList(crtshV3ActiveLogsListFilename); err == nil {
-					if MozillaV3Known, err = loadLogList(mozillaV3KnownLogsListFilename); err == nil {
-						if BimiV3Approved, err = loadLogList(bimiV3ApprovedLogsListFilename); err == nil {
-							LogMimics, err = loadLogList(logMimicsListFilename)
-						}
-					}
+// LogListByName returns the bundled log list with the given name (see LogListNames), or nil if there is no such log list.
+func LogListByName(name string) *loglist3.LogList {
+	for _, bll := range bundledLogLists {
+		if bll.name == name {
+			return *bll.logList
+		}
+	}
+	return nil
+}
+
+// LogListFilename returns the path, relative to the root of this repository, of the bundled log list with the given name (see LogListNames), or "" if there is no such log list.
+func LogListFilename(name string) string {
+	for _, bll := range bundledLogLists {
+		if bll.name == name {
+			return bll.filename
+		}
+	}
+	return ""
+}
+
+// LogSummary describes a log as it appears in a log list.
+type LogSummary struct {
+	Description string
+	URL         string // The submission URL, for a tiled log.
+	Operator    string
+	Type        string
+	Tiled       bool
+}
+
+// SummarizeLog describes the log identified by logID as it appears in the first of the bundled log lists (in LogListNames order) that includes it.
+func SummarizeLog(logID [sha256.Size]byte) (LogSummary, bool) {
+	for _, name := range LogListNames {
+		logList := LogListByName(name)
+		if logList == nil {
+			continue
+		}
+		for _, operator := range logList.Operators {
+			for _, log := range operator.Logs {
+				if toLogID(log.LogID) == logID {
+					return LogSummary{Description: log.Description, URL: log.URL, Operator: operator.Name, Type: log.Type}, true
+				}
+			}
+			for _, tiledLog := range operator.TiledLogs {
+				if toLogID(tiledLog.LogID) == logID {
+					return LogSummary{Description: tiledLog.Description, URL: tiledLog.SubmissionURL, Operator: operator.Name, Type: tiledLog.Type, Tiled: true}, true
 				}
 			}
 		}
 	}
+	return LogSummary{}, false
+}
 
-	return err
+// SummarizeLogs describes each log in logLists as it appears in the first of logLists that includes it.
+func SummarizeLogs(logLists ...*loglist3.LogList) map[[sha256.Size]byte]LogSummary {
+	summaries := make(map[[sha256.Size]byte]LogSummary)
+	for _, logList := range logLists {
+		if logList == nil {
+			continue
+		}
+		for _, operator := range logList.Operators {
+			for _, log := range operator.Logs {
+				if _, ok := summaries[toLogID(log.LogID)]; !ok {
+					summaries[toLogID(log.LogID)] = LogSummary{Description: log.Description, URL: log.URL, Operator: operator.Name, Type: log.Type}
+				}
+			}
+			for _, tiledLog := range operator.TiledLogs {
+				if _, ok := summaries[toLogID(tiledLog.LogID)]; !ok {
+					summaries[toLogID(tiledLog.LogID)] = LogSummary{Description: tiledLog.Description, URL: tiledLog.SubmissionURL, Operator: operator.Name, Type: tiledLog.Type, Tiled: true}
+				}
+			}
+		}
+	}
+	return summaries
 }
 
+// LoadAcceptedRoots must not run concurrently with any function that reads the Accepted Roots.
 func LoadAcceptedRoots() error {
+	// Start afresh, so that loading again doesn't duplicate quarantined roots or leave behind entries from an earlier load.
+	AcceptedRootsMap = make(map[[sha256.Size]byte]*x509util.PEMCertPool)
+	LogAcceptedRootsMap = make(map[[sha256.Size]byte][sha256.Size]byte)
+	LogEndpointTypeMap = make(map[[sha256.Size]byte]string)
+	QuarantinedRootsMap = make(map[[sha256.Size]byte][]QuarantinedRoot)
 	if dirEntry, err := files.ReadDir(acceptedRootsDir); err != nil {
 		return err
 	} else {
-		// Load the Accepted Roots lists.
+		// Load the Accepted Roots lists, quarantining any certificate that can't be parsed rather than rejecting the whole list.
+		quarantinedByRootsList := make(map[[sha256.Size]byte][]QuarantinedRoot)
+		sharedRoots := make(rootCertificateCache)
 		for _, file := range dirEntry {
 			if strings.HasPrefix(file.Name(), "roots_") {
 				decodedHash, err := hex.DecodeString(file.Name()[6:70])
@@ -76,14 +189,43 @@ func LoadAcceptedRoots() error {
 				if pemData, err = files.ReadFile(acceptedRootsDir + "/" + file.Name()); err != nil {
 					return err
 				}
-				if !AcceptedRootsMap[rootsListHash].AppendCertsFromPEM(pemData) {
-					return fmt.Errorf("failed to parse PEM data from %s", file.Name())
+				var block *pem.Block
+				for index := 0; ; index++ {
+					if block, pemData = pem.Decode(pemData); block == nil {
+						break
+					}
+					if cert, err := sharedRoots.certificate(block.Bytes); err != nil {
+						quarantinedByRootsList[rootsListHash] = append(quarantinedByRootsList[rootsListHash], unparseableRoot(file.Name(), index, block.Bytes, err))
+					} else {
+						AcceptedRootsMap[rootsListHash].AddCert(cert)
+					}
 				}
 			}
 		}
-		// Load the mapping of log IDs to Accepted Roots list hashes.
+		// Load the mapping of log IDs to Accepted Roots list hashes, and the types of endpoint from which they were fetched.
 		for _, file := range dirEntry {
-			if strings.HasPrefix(file.Name(), "log_") {
+			if strings.HasPrefix(file.Name(), "log_") && strings.HasSuffix(file.Name(), ".type") {
+				decodedHash, err := hex.DecodeString(file.Name()[4:68])
+				if err != nil {
+					return err
+				}
+				var data []byte
+				if data, err = files.ReadFile(acceptedRootsDir + "/" + file.Name()); err != nil {
+					return err
+				}
+				LogEndpointTypeMap[toLogID(decodedHash)] = strings.TrimSpace(string(data))
+			} else if strings.HasPrefix(file.Name(), "log_") && strings.HasSuffix(file.Name(), ".quarantine") {
+				decodedHash, err := hex.DecodeString(file.Name()[4:68])
+				if err != nil {
+					return err
+				}
+				logID := toLogID(decodedHash)
+				quarantined, err := readQuarantineFile(files, acceptedRootsDir+"/"+file.Name(), logID)
+				if err != nil {
+					return err
+				}
+				QuarantinedRootsMap[logID] = append(QuarantinedRootsMap[logID], quarantined...)
+			} else if strings.HasPrefix(file.Name(), "log_") && strings.HasSuffix(file.Name(), ".txt") {
 				decodedHash, err := hex.DecodeString(file.Name()[4:68])
 				if err != nil {
 					return err
@@ -98,12 +240,17 @@ func LoadAcceptedRoots() error {
 					var rootsListHash [sha256.Size]byte
 					copy(rootsListHash[:], decoded)
 					LogAcceptedRootsMap[logID] = rootsListHash
+					for _, qr := range quarantinedByRootsList[rootsListHash] {
+						qr.LogID = logID
+						QuarantinedRootsMap[logID] = append(QuarantinedRootsMap[logID], qr)
+					}
 				} else {
 					return err
 				}
 			}
 		}
 	}
+	resetAcceptedRootsIndex()
 	return nil
 }
 
@@ -118,6 +265,12 @@ func loadLogList(logListFilename string) (*loglist3.LogList, error) {
 	return logList, err
 }
 
+func toLogID(b []byte) [sha256.Size]byte {
+	var logID [sha256.Size]byte
+	copy(logID[:], b)
+	return logID
+}
+
 func populateMaps(logPublicKey []byte, ti *loglist3.TemporalInterval) error {
 	publicKey, err := x509.ParsePKIXPublicKey(logPublicKey)
 	if err != nil {
@@ -135,7 +288,9 @@ func populateMaps(logPublicKey []byte, ti *loglist3.TemporalInterval) error {
 
 	if ti != nil {
 		if TemporalIntervalMap[logID] == nil {
-			TemporalIntervalMap[logID] = ti
+			// Copy the interval, so that narrowing it below doesn't modify the log list it came from.
+			interval := *ti
+			TemporalIntervalMap[logID] = &interval
 		} else {
 			if ti.StartInclusive.After(TemporalIntervalMap[logID].StartInclusive) {
 				TemporalIntervalMap[logID].StartInclusive = ti.StartInclusive
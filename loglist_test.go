@@ -0,0 +1,38 @@
+package ctloglists
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+func TestTemporalIntervalMap(t *testing.T) {
+	if err := LoadLogLists(); err != nil {
+		t.Fatal(err)
+	}
+	temporalIntervalMap := TemporalIntervalMap
+	t.Cleanup(func() { TemporalIntervalMap = temporalIntervalMap })
+	TemporalIntervalMap = make(map[[sha256.Size]byte]*loglist3.TemporalInterval)
+
+	date := func(year, month int) time.Time {
+		return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	}
+	key := GstaticV3All.Operators[0].Logs[0].Key
+	first := &loglist3.TemporalInterval{StartInclusive: date(2025, 1), EndExclusive: date(2026, 1)}
+	second := &loglist3.TemporalInterval{StartInclusive: date(2025, 3), EndExclusive: date(2026, 3)}
+	for _, ti := range []*loglist3.TemporalInterval{first, second} {
+		if err := populateMaps(key, ti); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The log's interval is the intersection of those in each log list, without modifying the log lists' intervals.
+	if got := TemporalIntervalMap[sha256.Sum256(key)]; got == nil || !got.StartInclusive.Equal(date(2025, 3)) || !got.EndExclusive.Equal(date(2026, 1)) {
+		t.Errorf("TemporalIntervalMap = %v, want 2025-03 to 2026-01", got)
+	}
+	if !first.StartInclusive.Equal(date(2025, 1)) || !first.EndExclusive.Equal(date(2026, 1)) || !second.StartInclusive.Equal(date(2025, 3)) || !second.EndExclusive.Equal(date(2026, 3)) {
+		t.Errorf("populateMaps modified the log lists' intervals")
+	}
+}
@@ -0,0 +1,73 @@
+package ctloglists
+
+import (
+	"crypto/sha256"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// IsLogMimic returns true if logID identifies one of Chrome's "log mimics".
+func IsLogMimic(logID [sha256.Size]byte) bool {
+	if _, ok := LogMimicsMap[logID]; ok {
+		return true
+	}
+	_, ok := TiledLogMimicsMap[logID]
+	return ok
+}
+
+// GstaticV3AllWithMimics returns Chrome's all_logs_list.json with the Chrome log mimics folded in.
+// Chrome documents that a client whose log list is frozen should treat each mimic as a Usable log, run by an operator distinct from every real operator, so each mimic operator is appended as-is rather than merged into an existing operator.
+// The returned LogList shares its Operator and Log pointers with GstaticV3All and LogMimics, so callers must not modify them.
+func GstaticV3AllWithMimics() *loglist3.LogList {
+	if GstaticV3All == nil {
+		return nil
+	}
+	ll := *GstaticV3All
+	ll.Operators = append([]*loglist3.Operator{}, GstaticV3All.Operators...)
+	if LogMimics != nil {
+		ll.Operators = append(ll.Operators, LogMimics.Operators...)
+	}
+	return &ll
+}
+
+// WithoutLogMimics returns a copy of logList with any Chrome log mimics removed, along with any operators left with no logs.
+// The returned LogList shares its Log pointers with logList.
+func WithoutLogMimics(logList *loglist3.LogList) *loglist3.LogList {
+	if logList == nil {
+		return nil
+	}
+	ll := *logList
+	ll.Operators = nil
+	for _, operator := range logList.Operators {
+		op := *operator
+		op.Logs = []*loglist3.Log{}
+		for _, log := range operator.Logs {
+			if !IsLogMimic(toLogID(log.LogID)) {
+				op.Logs = append(op.Logs, log)
+			}
+		}
+		op.TiledLogs = []*loglist3.TiledLog{}
+		for _, tiledLog := range operator.TiledLogs {
+			if !IsLogMimic(toLogID(tiledLog.LogID)) {
+				op.TiledLogs = append(op.TiledLogs, tiledLog)
+			}
+		}
+		if len(op.Logs) > 0 || len(op.TiledLogs) > 0 {
+			ll.Operators = append(ll.Operators, &op)
+		}
+	}
+	return &ll
+}
+
+func populateLogMimicsMap() {
+	LogMimicsMap = make(map[[sha256.Size]byte]*loglist3.Log)
+	TiledLogMimicsMap = make(map[[sha256.Size]byte]*loglist3.TiledLog)
+	for _, operator := range LogMimics.Operators {
+		for _, log := range operator.Logs {
+			LogMimicsMap[toLogID(log.LogID)] = log
+		}
+		for _, tiledLog := range operator.TiledLogs {
+			TiledLogMimicsMap[toLogID(tiledLog.LogID)] = tiledLog
+		}
+	}
+}
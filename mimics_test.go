@@ -0,0 +1,94 @@
+package ctloglists
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+func countLogs(logList *loglist3.LogList) (logs, tiledLogs int) {
+	for _, operator := range logList.Operators {
+		logs += len(operator.Logs)
+		tiledLogs += len(operator.TiledLogs)
+	}
+	return logs, tiledLogs
+}
+
+func TestLogMimics(t *testing.T) {
+	if err := LoadLogLists(); err != nil {
+		t.Fatal(err)
+	}
+	if len(LogMimics.Operators) == 0 || len(LogMimics.Operators[0].Logs) == 0 {
+		t.Fatal("no bundled log mimics")
+	}
+	mimic := LogMimics.Operators[0].Logs[0]
+	if !IsLogMimic(toLogID(mimic.LogID)) {
+		t.Errorf("IsLogMimic(%x) = false for a log mimic", mimic.LogID)
+	}
+	realLog := GstaticV3All.Operators[0].Logs[0]
+	if IsLogMimic(toLogID(realLog.LogID)) {
+		t.Errorf("IsLogMimic(%x) = true for %s", realLog.LogID, realLog.Description)
+	}
+
+	gstaticOperators := len(GstaticV3All.Operators)
+	gstaticLogs, gstaticTiledLogs := countLogs(GstaticV3All)
+	mimicLogs, _ := countLogs(LogMimics)
+	withMimics := GstaticV3AllWithMimics()
+	// Each mimic operator is appended as-is, without modifying GstaticV3All.
+	if len(withMimics.Operators) != gstaticOperators+len(LogMimics.Operators) || len(GstaticV3All.Operators) != gstaticOperators {
+		t.Errorf("GstaticV3AllWithMimics has %d operators, want %d + %d", len(withMimics.Operators), gstaticOperators, len(LogMimics.Operators))
+	}
+	if logs, _ := countLogs(withMimics); logs != gstaticLogs+mimicLogs {
+		t.Errorf("GstaticV3AllWithMimics has %d logs, want %d + %d", logs, gstaticLogs, mimicLogs)
+	}
+	if withMimics.FindLogByKey(mimic.Key) == nil {
+		t.Errorf("GstaticV3AllWithMimics doesn't include %s", mimic.Description)
+	}
+	if withMimics.LogListTimestamp != GstaticV3All.LogListTimestamp {
+		t.Errorf("GstaticV3AllWithMimics changed the log list timestamp")
+	}
+
+	// Removing the mimics again leaves the real logs, and drops the mimic operators.
+	without := WithoutLogMimics(withMimics)
+	if logs, tiledLogs := countLogs(without); logs != gstaticLogs || tiledLogs != gstaticTiledLogs {
+		t.Errorf("WithoutLogMimics left %d logs and %d tiled logs, want %d and %d", logs, tiledLogs, gstaticLogs, gstaticTiledLogs)
+	}
+	if len(without.Operators) != gstaticOperators {
+		t.Errorf("WithoutLogMimics left %d operators, want %d", len(without.Operators), gstaticOperators)
+	}
+	if WithoutLogMimics(nil) != nil {
+		t.Errorf("WithoutLogMimics(nil) != nil")
+	}
+}
+
+func TestTiledLogMimics(t *testing.T) {
+	if err := LoadLogLists(); err != nil {
+		t.Fatal(err)
+	}
+	loadedLogMimics := LogMimics
+	t.Cleanup(func() {
+		LogMimics = loadedLogMimics
+		populateLogMimicsMap()
+	})
+
+	mimicID := sha256.Sum256([]byte("tiled mimic"))
+	realID := sha256.Sum256([]byte("real tiled log"))
+	LogMimics = &loglist3.LogList{Operators: []*loglist3.Operator{{Name: "Mimic Operator", TiledLogs: []*loglist3.TiledLog{{LogID: mimicID[:]}}}}}
+	populateLogMimicsMap()
+	if !IsLogMimic(mimicID) {
+		t.Errorf("IsLogMimic = false for a tiled log mimic")
+	}
+
+	logList := &loglist3.LogList{Operators: []*loglist3.Operator{
+		{Name: "Real Operator", TiledLogs: []*loglist3.TiledLog{{LogID: realID[:]}, {LogID: mimicID[:]}}},
+		{Name: "Mimic Operator", TiledLogs: []*loglist3.TiledLog{{LogID: mimicID[:]}}},
+	}}
+	without := WithoutLogMimics(logList)
+	if len(without.Operators) != 1 || len(without.Operators[0].TiledLogs) != 1 || string(without.Operators[0].TiledLogs[0].LogID) != string(realID[:]) {
+		t.Errorf("WithoutLogMimics didn't remove only the tiled log mimic")
+	}
+	if len(logList.Operators[0].TiledLogs) != 2 {
+		t.Errorf("WithoutLogMimics modified its argument")
+	}
+}
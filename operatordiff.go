@@ -0,0 +1,165 @@
+package ctloglists
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// DiffOperator identifies an operator in an OperatorDiff.
+type DiffOperator struct {
+	Name  string   `json:"name"`
+	Email []string `json:"email"`
+}
+
+// OperatorRename is an operator whose name differs between two log lists.
+type OperatorRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// OperatorEmailChange is an operator whose contact email addresses differ between two log lists.
+type OperatorEmailChange struct {
+	Operator string   `json:"operator"` // The operator's name in the second list.
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+}
+
+// LogMove is a log whose operator differs between two log lists.
+type LogMove struct {
+	DiffLog
+	FromOperator string `json:"from_operator"`
+	ToOperator   string `json:"to_operator"`
+}
+
+// OperatorDiff describes the differences between the operators of two log lists.
+// Operators are matched by the logs they run rather than by name, so that a renamed operator is reported as such: each operator in the first list is matched with the operator in the second list of the same name that runs any of the same logs, or else the one that runs the most of the same logs, or failing that, with an operator of the same name.
+type OperatorDiff struct {
+	Added        []DiffOperator        `json:"added"`
+	Removed      []DiffOperator        `json:"removed"`
+	Renamed      []OperatorRename      `json:"renamed"`
+	EmailChanges []OperatorEmailChange `json:"email_changes"`
+	LogMoves     []LogMove             `json:"log_moves"`
+}
+
+// Empty returns true if the operators have no differences.
+func (d OperatorDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Renamed) == 0 && len(d.EmailChanges) == 0 && len(d.LogMoves) == 0
+}
+
+// DiffOperators compares the operators of log lists a and b.
+func DiffOperators(a, b *loglist3.LogList) OperatorDiff {
+	diff := OperatorDiff{Added: []DiffOperator{}, Removed: []DiffOperator{}, Renamed: []OperatorRename{}, EmailChanges: []OperatorEmailChange{}, LogMoves: []LogMove{}}
+
+	// Find the operator of each log in b, keyed by the log's public key.
+	logOperatorB := make(map[string]int)
+	for j, operator := range b.Operators {
+		for _, log := range operator.Logs {
+			logOperatorB[string(log.Key)] = j
+		}
+		for _, log := range operator.TiledLogs {
+			logOperatorB[string(log.Key)] = j
+		}
+	}
+
+	// Count the logs that each pair of operators have in common.
+	type pair struct {
+		i, j, shared int
+		sameName     bool
+	}
+	var pairs []pair
+	for i, operator := range a.Operators {
+		shared := make(map[int]int)
+		for _, log := range operator.Logs {
+			if j, ok := logOperatorB[string(log.Key)]; ok {
+				shared[j]++
+			}
+		}
+		for _, log := range operator.TiledLogs {
+			if j, ok := logOperatorB[string(log.Key)]; ok {
+				shared[j]++
+			}
+		}
+		for j, n := range shared {
+			pairs = append(pairs, pair{i: i, j: j, shared: n, sameName: operator.Name == b.Operators[j].Name})
+		}
+	}
+	slices.SortFunc(pairs, func(x, y pair) int {
+		if x.sameName != y.sameName {
+			if x.sameName {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(y.shared, x.shared), cmp.Compare(x.i, y.i), cmp.Compare(x.j, y.j))
+	})
+
+	// Greedily match the operators with the same name and any logs in common (so that an operator that acquires another's logs isn't reported as a rename), then those with the most logs in common, then any remaining operators by name.
+	matchA := make(map[int]int)
+	matchB := make(map[int]int)
+	for _, p := range pairs {
+		if _, ok := matchA[p.i]; !ok {
+			if _, ok := matchB[p.j]; !ok {
+				matchA[p.i], matchB[p.j] = p.j, p.i
+			}
+		}
+	}
+	for i, operatorA := range a.Operators {
+		if _, ok := matchA[i]; ok {
+			continue
+		}
+		for j, operatorB := range b.Operators {
+			if _, ok := matchB[j]; !ok && operatorA.Name == operatorB.Name {
+				matchA[i], matchB[j] = j, i
+				break
+			}
+		}
+	}
+
+	for i, operatorA := range a.Operators {
+		// Report the logs now run by an operator other than the one matched with their previous operator.
+		j, ok := matchA[i]
+		for _, log := range operatorA.Logs {
+			if k, found := logOperatorB[string(log.Key)]; found && (!ok || k != j) {
+				diff.LogMoves = append(diff.LogMoves, LogMove{DiffLog: viewLog(operatorA.Name, log).DiffLog, FromOperator: operatorA.Name, ToOperator: b.Operators[k].Name})
+			}
+		}
+		for _, log := range operatorA.TiledLogs {
+			if k, found := logOperatorB[string(log.Key)]; found && (!ok || k != j) {
+				diff.LogMoves = append(diff.LogMoves, LogMove{DiffLog: viewTiledLog(operatorA.Name, log).DiffLog, FromOperator: operatorA.Name, ToOperator: b.Operators[k].Name})
+			}
+		}
+
+		if !ok {
+			diff.Removed = append(diff.Removed, DiffOperator{Name: operatorA.Name, Email: operatorA.Email})
+			continue
+		}
+		operatorB := b.Operators[j]
+		if operatorA.Name != operatorB.Name {
+			diff.Renamed = append(diff.Renamed, OperatorRename{From: operatorA.Name, To: operatorB.Name})
+		}
+		change := OperatorEmailChange{Operator: operatorB.Name, Added: []string{}, Removed: []string{}}
+		for _, email := range operatorB.Email {
+			if !slices.Contains(operatorA.Email, email) {
+				change.Added = append(change.Added, email)
+			}
+		}
+		for _, email := range operatorA.Email {
+			if !slices.Contains(operatorB.Email, email) {
+				change.Removed = append(change.Removed, email)
+			}
+		}
+		if len(change.Added) > 0 || len(change.Removed) > 0 {
+			diff.EmailChanges = append(diff.EmailChanges, change)
+		}
+	}
+
+	for j, operatorB := range b.Operators {
+		if _, ok := matchB[j]; !ok {
+			diff.Added = append(diff.Added, DiffOperator{Name: operatorB.Name, Email: operatorB.Email})
+		}
+	}
+
+	return diff
+}
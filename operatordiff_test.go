@@ -0,0 +1,81 @@
+package ctloglists
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+func TestDiffOperators(t *testing.T) {
+	log1, log2, log3 := newTestLog("log1", testUsable), newTestLog("log2", testUsable), newTestLog("log3", testUsable)
+	tiled := newTestTiledLog("tiled", testUsable)
+	operator := func(name string, email []string, logs []*loglist3.Log, tiledLogs ...*loglist3.TiledLog) *loglist3.Operator {
+		return &loglist3.Operator{Name: name, Email: email, Logs: logs, TiledLogs: tiledLogs}
+	}
+	for _, test := range []struct {
+		name string
+		a, b *loglist3.LogList
+		want []string
+	}{
+		{
+			name: "identical",
+			a:    testLogList(operator("A", []string{"a@example.com"}, []*loglist3.Log{log1}, tiled)),
+			b:    testLogList(operator("A", []string{"a@example.com"}, []*loglist3.Log{log1}, tiled)),
+		},
+		{
+			// Matched by the logs they run, not by name.
+			name: "renamed",
+			a:    testLogList(operator("A", nil, []*loglist3.Log{log1, log2}), operator("B", nil, []*loglist3.Log{log3})),
+			b:    testLogList(operator("B", nil, []*loglist3.Log{log3}), operator("A Ltd", nil, []*loglist3.Log{log1, log2})),
+			want: []string{"renamed operator A -> A Ltd"},
+		},
+		{
+			name: "renamed, with new email addresses",
+			a:    testLogList(operator("A", []string{"old@example.com", "both@example.com"}, nil, tiled)),
+			b:    testLogList(operator("A2", []string{"both@example.com", "new@example.com"}, nil, tiled)),
+			want: []string{"renamed operator A -> A2", "email A2: +[new@example.com] -[old@example.com]"},
+		},
+		{
+			name: "log moved",
+			a:    testLogList(operator("A", nil, []*loglist3.Log{log1, log2}), operator("B", nil, []*loglist3.Log{log3})),
+			b:    testLogList(operator("A", nil, []*loglist3.Log{log1}), operator("B", nil, []*loglist3.Log{log2, log3})),
+			want: []string{"moved log2: A -> B"},
+		},
+		{
+			name: "tiled log moved",
+			a:    testLogList(operator("A", nil, []*loglist3.Log{log1}, tiled), operator("B", nil, []*loglist3.Log{log2})),
+			b:    testLogList(operator("A", nil, []*loglist3.Log{log1}), operator("B", nil, []*loglist3.Log{log2}, tiled)),
+			want: []string{"moved tiled: A -> B"},
+		},
+		{
+			// Every log moved to another operator, e.g. after an acquisition.
+			name: "acquired",
+			a:    testLogList(operator("A", nil, []*loglist3.Log{log1, log2}), operator("B", nil, []*loglist3.Log{log3})),
+			b:    testLogList(operator("B", nil, []*loglist3.Log{log1, log2, log3})),
+			want: []string{"removed operator A", "moved log1: A -> B", "moved log2: A -> B"},
+		},
+		{
+			// Logs split off to a new operator.
+			name: "split",
+			a:    testLogList(operator("A", nil, []*loglist3.Log{log1, log2, log3})),
+			b:    testLogList(operator("A", nil, []*loglist3.Log{log1, log2}), operator("C", nil, []*loglist3.Log{log3})),
+			want: []string{"added operator C", "moved log3: A -> C"},
+		},
+		{
+			// Operators without logs in common are matched by name.
+			name: "no logs in common",
+			a:    testLogList(operator("A", []string{"a@example.com"}, []*loglist3.Log{log1}), operator("B", nil, []*loglist3.Log{log2})),
+			b:    testLogList(operator("A", []string{"a2@example.com"}, []*loglist3.Log{log3}), operator("D", nil, nil)),
+			want: []string{"added operator D", "removed operator B", "email A: +[a2@example.com] -[a@example.com]"},
+		},
+	} {
+		diff := DiffOperators(test.a, test.b)
+		if got := describeDiff(ListDiff{Operators: diff}); !slices.Equal(got, test.want) {
+			t.Errorf("%s: DiffOperators = %q, want %q", test.name, got, test.want)
+		}
+		if diff.Empty() != (len(test.want) == 0) {
+			t.Errorf("%s: Empty() = %v", test.name, diff.Empty())
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package rootsfetcher fetches CT logs' Accepted Roots from their get-roots endpoints.
+package rootsfetcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config configures a Fetcher. Zero fields take the defaults described below.
+type Config struct {
+	// HTTPClient is used for all requests. Defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+	// Concurrency is the maximum number of logs fetched from at once. Defaults to 16.
+	Concurrency int
+	// MaxAttempts is the maximum number of attempts per log. Defaults to 5.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, which doubles (with jitter) for each subsequent retry. Defaults to 5 seconds.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between retries. Defaults to 1 minute.
+	MaxBackoff time.Duration
+	// PerHostInterval is the minimum interval between the starts of requests to the same host. Defaults to 250 milliseconds.
+	PerHostInterval time.Duration
+	// RewriteURL, if set, rewrites each get-roots URL before it is requested (e.g. to point at an httptest.Server).
+	RewriteURL func(string) string
+	// Logf, if set, is called to report progress.
+	Logf func(format string, args ...any)
+}
+
+// Target identifies a log to fetch Accepted Roots from.
+type Target struct {
+	// BaseURL is an RFC 6962 log's base URL, or a static-ct-api log's submission prefix.
+	BaseURL      string
+	LogID        [sha256.Size]byte
+	EndpointType string
+}
+
+// Result is the outcome of fetching Accepted Roots from a Target.
+type Result struct {
+	Target
+	// Certificates holds the base64-encoded DER certificates returned by get-roots, exactly as returned.
+	Certificates []string
+	Attempts     int
+	Err          error
+}
+
+// Fetcher fetches Accepted Roots from many logs concurrently, retrying with exponential backoff and rate-limiting requests to each host.
+type Fetcher struct {
+	config Config
+
+	mutex        sync.Mutex
+	nextRequests map[string]time.Time
+}
+
+// New returns a Fetcher configured by config.
+func New(config Config) *Fetcher {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 16
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 5 * time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = time.Minute
+	}
+	if config.PerHostInterval <= 0 {
+		config.PerHostInterval = 250 * time.Millisecond
+	}
+	if config.Logf == nil {
+		config.Logf = func(string, ...any) {}
+	}
+	return &Fetcher{config: config, nextRequests: make(map[string]time.Time)}
+}
+
+// GetRootsURL returns the get-roots URL for baseURL. This is the same for RFC 6962 logs and static-ct-api logs, relative to the base URL or submission prefix respectively.
+func GetRootsURL(baseURL string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/ct/v1/get-roots"
+}
+
+// Fetch fetches Accepted Roots from each target, returning one Result per target in the same order.
+// If ctx is cancelled, outstanding fetches fail with ctx's error.
+func (f *Fetcher) Fetch(ctx context.Context, targets []Target) []Result {
+	results := make([]Result, len(targets))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(f.config.Concurrency, len(targets)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = f.FetchOne(ctx, targets[i])
+			}
+		}()
+	}
+	for i := range targets {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// FetchOne fetches Accepted Roots from target, retrying up to MaxAttempts times.
+func (f *Fetcher) FetchOne(ctx context.Context, target Target) Result {
+	result := Result{Target: target}
+	getRootsURL := GetRootsURL(target.BaseURL)
+	if f.config.RewriteURL != nil {
+		getRootsURL = f.config.RewriteURL(getRootsURL)
+	}
+
+	for result.Attempts = 1; ; result.Attempts++ {
+		if result.Certificates, result.Err = f.getRoots(ctx, getRootsURL); result.Err == nil {
+			f.config.Logf("Successfully downloaded accepted roots from %s\n", getRootsURL)
+			return result
+		}
+		f.config.Logf("Error fetching %s (attempt %d/%d): %v\n", getRootsURL, result.Attempts, f.config.MaxAttempts, result.Err)
+		if result.Attempts >= f.config.MaxAttempts || ctx.Err() != nil {
+			break
+		}
+		if err := sleep(ctx, f.backoff(result.Attempts)); err != nil {
+			result.Err = err
+			break
+		}
+	}
+
+	f.config.Logf("Failed to download accepted roots from %s after %d attempts\n", getRootsURL, result.Attempts)
+	return result
+}
+
+func (f *Fetcher) getRoots(ctx context.Context, getRootsURL string) ([]string, error) {
+	if err := f.waitForHost(ctx, getRootsURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getRootsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("non-OK status: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+	var response struct {
+		Certificates []string `json:"certificates"`
+	}
+	if err = json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error decoding JSON: %v", err)
+	}
+	return response.Certificates, nil
+}
+
+// waitForHost blocks until a request to getRootsURL's host is permitted by PerHostInterval.
+func (f *Fetcher) waitForHost(ctx context.Context, getRootsURL string) error {
+	u, err := url.Parse(getRootsURL)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	now := time.Now()
+	next := f.nextRequests[u.Host]
+	if next.Before(now) {
+		next = now
+	}
+	f.nextRequests[u.Host] = next.Add(f.config.PerHostInterval)
+	f.mutex.Unlock()
+
+	return sleep(ctx, time.Until(next))
+}
+
+// backoff returns the delay before the retry following the specified attempt: exponential, capped at MaxBackoff, with jitter of up to half the delay.
+func (f *Fetcher) backoff(attempt int) time.Duration {
+	delay := f.config.InitialBackoff
+	for i := 1; i < attempt && delay < f.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, f.config.MaxBackoff)
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,229 @@
+package rootsfetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a server whose get-roots endpoint fails with a 503 for the first failures requests, then returns two certificates.
+// requests counts all requests received.
+func newTestServer(t *testing.T, failures int32) (ts *httptest.Server, requests *atomic.Int32) {
+	t.Helper()
+	requests = new(atomic.Int32)
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/log/ct/v1/get-roots" {
+			http.NotFound(w, r)
+			return
+		}
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"certificates":["AAAA","BBBB"]}`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, requests
+}
+
+// newTestFetcher returns a Fetcher that retries with millisecond backoff and doesn't rate-limit, unless config says otherwise.
+func newTestFetcher(config Config) *Fetcher {
+	if config.InitialBackoff == 0 {
+		config.InitialBackoff = time.Millisecond
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = 2 * time.Millisecond
+	}
+	if config.PerHostInterval == 0 {
+		config.PerHostInterval = time.Nanosecond
+	}
+	return New(config)
+}
+
+func TestGetRootsURL(t *testing.T) {
+	for _, baseURL := range []string{"https://ct.example.com/log", "https://ct.example.com/log/"} {
+		if got := GetRootsURL(baseURL); got != "https://ct.example.com/log/ct/v1/get-roots" {
+			t.Errorf("GetRootsURL(%q) = %q", baseURL, got)
+		}
+	}
+}
+
+func TestFetchOneRetries(t *testing.T) {
+	ts, requests := newTestServer(t, 2)
+	f := newTestFetcher(Config{HTTPClient: ts.Client(), MaxAttempts: 3})
+
+	result := f.FetchOne(context.Background(), Target{BaseURL: ts.URL + "/log"})
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if result.Attempts != 3 || requests.Load() != 3 {
+		t.Errorf("Attempts = %d after %d requests, want 3", result.Attempts, requests.Load())
+	}
+	if strings.Join(result.Certificates, ",") != "AAAA,BBBB" {
+		t.Errorf("Certificates = %v, want [AAAA BBBB]", result.Certificates)
+	}
+}
+
+func TestFetchOneGivesUp(t *testing.T) {
+	ts, requests := newTestServer(t, 3)
+	f := newTestFetcher(Config{HTTPClient: ts.Client(), MaxAttempts: 3})
+
+	result := f.FetchOne(context.Background(), Target{BaseURL: ts.URL + "/log"})
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "503") {
+		t.Errorf("Err = %v, want a non-OK status error", result.Err)
+	}
+	if result.Attempts != 3 || requests.Load() != 3 {
+		t.Errorf("Attempts = %d after %d requests, want 3", result.Attempts, requests.Load())
+	}
+}
+
+func TestFetchOneErrors(t *testing.T) {
+	for _, test := range []struct {
+		name, body, want string
+	}{
+		{"bad JSON", `{"certificates":`, "error decoding JSON"},
+		{"wrong type", `{"certificates":"AAAA"}`, "error decoding JSON"},
+	} {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(test.body))
+		}))
+		f := newTestFetcher(Config{HTTPClient: ts.Client(), MaxAttempts: 1})
+		if result := f.FetchOne(context.Background(), Target{BaseURL: ts.URL}); result.Err == nil || !strings.Contains(result.Err.Error(), test.want) {
+			t.Errorf("%s: Err = %v, want %q", test.name, result.Err, test.want)
+		}
+		ts.Close()
+	}
+
+	ts, _ := newTestServer(t, 0)
+	f := newTestFetcher(Config{HTTPClient: ts.Client(), MaxAttempts: 1})
+	if result := f.FetchOne(context.Background(), Target{BaseURL: ts.URL + "/missing"}); result.Err == nil || !strings.Contains(result.Err.Error(), "404") {
+		t.Errorf("Err = %v, want a non-OK status error", result.Err)
+	}
+}
+
+func TestFetchOneCancelledDuringBackoff(t *testing.T) {
+	ts, requests := newTestServer(t, 1)
+	f := New(Config{HTTPClient: ts.Client(), MaxAttempts: 5, InitialBackoff: time.Hour, MaxBackoff: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	f.config.Logf = func(format string, args ...any) {
+		// Cancel once FetchOne is waiting to retry.
+		if strings.HasPrefix(format, "Error fetching") {
+			time.AfterFunc(10*time.Millisecond, cancel)
+		}
+	}
+
+	start := time.Now()
+	result := f.FetchOne(ctx, Target{BaseURL: ts.URL + "/log"})
+	if !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("Err = %v, want context.Canceled", result.Err)
+	}
+	if result.Attempts != 1 || requests.Load() != 1 {
+		t.Errorf("Attempts = %d after %d requests, want 1", result.Attempts, requests.Load())
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("FetchOne took %v to notice cancellation", elapsed)
+	}
+}
+
+func TestFetchCancelled(t *testing.T) {
+	ts, requests := newTestServer(t, 0)
+	f := newTestFetcher(Config{HTTPClient: ts.Client()})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, result := range f.Fetch(ctx, []Target{{BaseURL: ts.URL + "/log"}, {BaseURL: ts.URL + "/log"}}) {
+		if !errors.Is(result.Err, context.Canceled) || result.Attempts != 1 {
+			t.Errorf("Err = %v after %d attempts, want context.Canceled after 1", result.Err, result.Attempts)
+		}
+	}
+	if requests.Load() != 0 {
+		t.Errorf("%d requests made after cancellation", requests.Load())
+	}
+}
+
+func TestFetchPerHostInterval(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	var mutex sync.Mutex
+	var starts []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		starts = append(starts, time.Now())
+		mutex.Unlock()
+		w.Write([]byte(`{"certificates":[]}`))
+	}))
+	defer ts.Close()
+	f := newTestFetcher(Config{HTTPClient: ts.Client(), Concurrency: 4, PerHostInterval: interval})
+
+	targets := make([]Target, 4)
+	for i := range targets {
+		targets[i].BaseURL = ts.URL
+	}
+	for _, result := range f.Fetch(context.Background(), targets) {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+	}
+	if len(starts) != len(targets) {
+		t.Fatalf("%d requests, want %d", len(starts), len(targets))
+	}
+	// Allow for requests reaching the server slightly less than interval apart, having started no less than interval apart.
+	for i := 1; i < len(starts); i++ {
+		if gap := starts[i].Sub(starts[i-1]); gap < interval*4/5 {
+			t.Errorf("requests %d and %d were %v apart, want at least %v", i-1, i, gap, interval)
+		}
+	}
+}
+
+func TestFetchRewriteURL(t *testing.T) {
+	ts, requests := newTestServer(t, 0)
+	var rewritten []string
+	f := newTestFetcher(Config{
+		HTTPClient:  ts.Client(),
+		Concurrency: 1,
+		RewriteURL: func(getRootsURL string) string {
+			rewritten = append(rewritten, getRootsURL)
+			return strings.Replace(getRootsURL, "https://ct.example.com", ts.URL, 1)
+		},
+	})
+
+	results := f.Fetch(context.Background(), []Target{{BaseURL: "https://ct.example.com/log/", EndpointType: "rfc6962"}})
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("%d requests reached the test server, want 1", requests.Load())
+	}
+	if len(rewritten) != 1 || rewritten[0] != "https://ct.example.com/log/ct/v1/get-roots" {
+		t.Errorf("RewriteURL called with %q, want the get-roots URL", rewritten)
+	}
+	if results[0].BaseURL != "https://ct.example.com/log/" || results[0].EndpointType != "rfc6962" {
+		t.Errorf("Result's Target = %+v, want the unrewritten Target", results[0].Target)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	f := New(Config{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	for _, test := range []struct {
+		attempt int
+		delay   time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{100, 5 * time.Second},
+	} {
+		for range 100 {
+			if got := f.backoff(test.attempt); got < test.delay/2 || got > test.delay {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", test.attempt, got, test.delay/2, test.delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package ctloglists
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+)
+
+// ShardFamily is a set of temporal shards (e.g. Google's yearly "Argon" shards) run by one operator.
+type ShardFamily struct {
+	Operator string
+	Name     string
+	LogIDs   [][sha256.Size]byte // In ascending order of temporal interval start.
+}
+
+// ShardRootsReport compares the Accepted Roots of the shards in a ShardFamily.
+type ShardRootsReport struct {
+	Family     ShardFamily
+	Consistent bool
+	Shards     []ShardRoots
+	Missing    []MissingShardRoot // Roots accepted by some, but not all, of the shards with known Accepted Roots.
+}
+
+// ShardRoots describes one shard's Accepted Roots. RootsListHash is nil if the shard's Accepted Roots are not known.
+type ShardRoots struct {
+	LogID         [sha256.Size]byte
+	Description   string
+	RootsListHash *[sha256.Size]byte
+	Roots         int
+}
+
+// MissingShardRoot is a root accepted by some of a ShardFamily's shards but missing from others.
+type MissingShardRoot struct {
+	Root        AcceptedRoot
+	MissingFrom [][sha256.Size]byte
+}
+
+// shardNameRegexp matches a shard's family name followed by its year, optional half-year and optional suffix, e.g. "Argon2026h1", "Nimbus 2025", "Raio2025h2b" or "Yeti 2022' log #2".
+var shardNameRegexp = regexp.MustCompile(`^(.*?[A-Za-z])\s*20\d\d(?:[hH][12])?[a-z]?\b`)
+
+// ShardFamilyName returns the family name of a temporal shard from its log list description, ignoring any operator name prefix, quotes and case. It returns false if the description doesn't name a temporal shard.
+func ShardFamilyName(operator, description string) (string, bool) {
+	name := strings.TrimSpace(strings.TrimPrefix(description, operator))
+	name = strings.TrimLeft(name, "'\" ")
+	matches := shardNameRegexp.FindStringSubmatch(name)
+	if matches == nil {
+		return "", false
+	}
+	return strings.ToLower(strings.Trim(matches[1], "'\" ")), true
+}
+
+// ShardFamilies groups the logs and tiled logs in logList into families of temporal shards by operator and family name, returning the families (ordered by operator and name) that have at least two shards.
+func ShardFamilies(logList *loglist3.LogList) []ShardFamily {
+	type shard struct {
+		logID [sha256.Size]byte
+		start int64
+	}
+	var families []ShardFamily
+	for _, operator := range logList.Operators {
+		shards := make(map[string][]shard)
+		add := func(logID []byte, description string, ti *loglist3.TemporalInterval) {
+			if name, ok := ShardFamilyName(operator.Name, description); ok && !IsLogMimic(toLogID(logID)) {
+				s := shard{logID: toLogID(logID)}
+				if ti != nil {
+					s.start = ti.StartInclusive.Unix()
+				}
+				shards[name] = append(shards[name], s)
+			}
+		}
+		for _, log := range operator.Logs {
+			add(log.LogID, log.Description, log.TemporalInterval)
+		}
+		for _, tiledLog := range operator.TiledLogs {
+			add(tiledLog.LogID, tiledLog.Description, tiledLog.TemporalInterval)
+		}
+
+		for name, s := range shards {
+			if len(s) < 2 {
+				continue
+			}
+			slices.SortFunc(s, func(a, b shard) int {
+				return cmp.Or(cmp.Compare(a.start, b.start), bytes.Compare(a.logID[:], b.logID[:]))
+			})
+			family := ShardFamily{Operator: operator.Name, Name: name}
+			for _, sh := range s {
+				family.LogIDs = append(family.LogIDs, sh.logID)
+			}
+			families = append(families, family)
+		}
+	}
+	slices.SortFunc(families, func(a, b ShardFamily) int {
+		return cmp.Or(cmp.Compare(a.Operator, b.Operator), cmp.Compare(a.Name, b.Name))
+	})
+	return families
+}
+
+// CompareShardRoots compares the Accepted Roots of the shards in family. The family is consistent if its shards accept the same set of roots, even if their Accepted Roots lists differ in other ways (e.g. if one of them includes a certificate that couldn't be parsed). Shards whose Accepted Roots are not known are reported, but don't make the family inconsistent. Requires LoadAcceptedRoots.
+func CompareShardRoots(family ShardFamily) ShardRootsReport {
+	report := ShardRootsReport{Family: family}
+	var known [][sha256.Size]byte
+	for _, logID := range family.LogIDs {
+		shard := ShardRoots{LogID: logID}
+		if summary, ok := SummarizeLog(logID); ok {
+			shard.Description = summary.Description
+		}
+		if rootsListHash, ok := LogAcceptedRootsMap[logID]; ok && AcceptedRootsMap[rootsListHash] != nil {
+			shard.RootsListHash = &rootsListHash
+			shard.Roots = len(AcceptedRootsForLog(logID))
+			known = append(known, logID)
+		}
+		report.Shards = append(report.Shards, shard)
+	}
+
+	// Report each root in the union of the shards' Accepted Roots that's missing from any shard.
+	accepting := make(map[[sha256.Size]byte]map[[sha256.Size]byte]bool)
+	var union []AcceptedRoot
+	for _, logID := range known {
+		for _, root := range AcceptedRootsForLog(logID) {
+			if accepting[root.Fingerprint] == nil {
+				accepting[root.Fingerprint] = make(map[[sha256.Size]byte]bool)
+				union = append(union, root)
+			}
+			accepting[root.Fingerprint][logID] = true
+		}
+	}
+	for _, root := range union {
+		if len(accepting[root.Fingerprint]) == len(known) {
+			continue
+		}
+		missing := MissingShardRoot{Root: root}
+		for _, logID := range known {
+			if !accepting[root.Fingerprint][logID] {
+				missing.MissingFrom = append(missing.MissingFrom, logID)
+			}
+		}
+		report.Missing = append(report.Missing, missing)
+	}
+	slices.SortFunc(report.Missing, func(a, b MissingShardRoot) int {
+		return cmp.Or(cmp.Compare(a.Root.Subject, b.Root.Subject), bytes.Compare(a.Root.Fingerprint[:], b.Root.Fingerprint[:]))
+	})
+	report.Consistent = len(report.Missing) == 0
+	return report
+}
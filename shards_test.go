@@ -0,0 +1,145 @@
+package ctloglists
+
+import (
+	"crypto/sha256"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/certificate-transparency-go/loglist3"
+	"github.com/google/certificate-transparency-go/x509"
+	"github.com/google/certificate-transparency-go/x509util"
+)
+
+func TestShardFamilyName(t *testing.T) {
+	for _, test := range []struct {
+		operator, description string
+		want                  string
+		ok                    bool
+	}{
+		{"Google", "Google 'Argon2026h1' log", "argon", true},
+		{"Google", "Google 'Argon2026h2'", "argon", true},
+		{"Cloudflare", "Cloudflare 'Nimbus2025'", "nimbus", true},
+		{"Sectigo", "Sectigo 'Elephant2027h1'", "elephant", true},
+		{"DigiCert", "DigiCert Yeti2022-2 Log", "yeti", true},
+		{"Let's Encrypt", "Let's Encrypt 'Oak2025h2'", "oak", true},
+		{"TrustAsia", "TrustAsia Log2025a", "log", true},
+		{"Geomys", "Tuscolo2026h1", "tuscolo", true},
+		{"Some Operator", "Some Operator 'RAIO2025H2b' log", "raio", true},
+		{"Google", "Google 'Pilot' log", "", false},
+		{"Google", "Google 'Solera2018'", "solera", true},
+		{"Google", "2026", "", false},
+		{"Google", "", "", false},
+	} {
+		if got, ok := ShardFamilyName(test.operator, test.description); got != test.want || ok != test.ok {
+			t.Errorf("ShardFamilyName(%q, %q) = %q, %v, want %q, %v", test.operator, test.description, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestShardFamilies(t *testing.T) {
+	logID := func(description string) []byte {
+		id := sha256.Sum256([]byte(description))
+		return id[:]
+	}
+	interval := func(year int) *loglist3.TemporalInterval {
+		return &loglist3.TemporalInterval{StartInclusive: time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), EndExclusive: time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	logList := &loglist3.LogList{Operators: []*loglist3.Operator{
+		{Name: "Zeta", Logs: []*loglist3.Log{
+			{LogID: logID("Zeta 'Gamma2026'"), Description: "Zeta 'Gamma2026'", TemporalInterval: interval(2026)},
+			{LogID: logID("Zeta 'Gamma2025'"), Description: "Zeta 'Gamma2025'", TemporalInterval: interval(2025)},
+			// The only shard in its family.
+			{LogID: logID("Zeta 'Delta2025'"), Description: "Zeta 'Delta2025'", TemporalInterval: interval(2025)},
+			// Not a temporal shard.
+			{LogID: logID("Zeta 'Pilot'"), Description: "Zeta 'Pilot'"},
+		}},
+		// RFC 6962 logs and tiled logs can be shards of the same family, regardless of case.
+		{Name: "Alpha", Logs: []*loglist3.Log{
+			{LogID: logID("Alpha 'Beta2025'"), Description: "Alpha 'Beta2025'", TemporalInterval: interval(2025)},
+		}, TiledLogs: []*loglist3.TiledLog{
+			{LogID: logID("Alpha 'beta2026'"), Description: "Alpha 'beta2026'", TemporalInterval: interval(2026)},
+		}},
+		// Families are grouped by operator.
+		{Name: "Eta", Logs: []*loglist3.Log{
+			{LogID: logID("Eta 'Gamma2025'"), Description: "Eta 'Gamma2025'", TemporalInterval: interval(2025)},
+		}},
+	}}
+
+	families := ShardFamilies(logList)
+	want := []ShardFamily{
+		{Operator: "Alpha", Name: "beta", LogIDs: [][sha256.Size]byte{toLogID(logID("Alpha 'Beta2025'")), toLogID(logID("Alpha 'beta2026'"))}},
+		{Operator: "Zeta", Name: "gamma", LogIDs: [][sha256.Size]byte{toLogID(logID("Zeta 'Gamma2025'")), toLogID(logID("Zeta 'Gamma2026'"))}},
+	}
+	if !slices.EqualFunc(families, want, func(a, b ShardFamily) bool {
+		return a.Operator == b.Operator && a.Name == b.Name && slices.Equal(a.LogIDs, b.LogIDs)
+	}) {
+		t.Errorf("ShardFamilies = %+v, want %+v", families, want)
+	}
+}
+
+// useShardRoots replaces the Accepted Roots with pools, each of which is used by the log whose ID is the same index in logIDs, until the test finishes.
+func useShardRoots(t *testing.T, logIDs [][sha256.Size]byte, pools []*x509util.PEMCertPool) {
+	t.Helper()
+	acceptedRootsMap, logAcceptedRootsMap := AcceptedRootsMap, LogAcceptedRootsMap
+	t.Cleanup(func() {
+		AcceptedRootsMap, LogAcceptedRootsMap = acceptedRootsMap, logAcceptedRootsMap
+		resetAcceptedRootsIndex()
+	})
+	AcceptedRootsMap = make(map[[sha256.Size]byte]*x509util.PEMCertPool)
+	LogAcceptedRootsMap = make(map[[sha256.Size]byte][sha256.Size]byte)
+	for i, pool := range pools {
+		rootsListHash := sha256.Sum256([]byte{byte(i)})
+		AcceptedRootsMap[rootsListHash] = pool
+		LogAcceptedRootsMap[logIDs[i]] = rootsListHash
+	}
+	resetAcceptedRootsIndex()
+}
+
+func TestCompareShardRoots(t *testing.T) {
+	if err := LoadLogLists(); err != nil {
+		t.Fatal(err)
+	} else if err = LoadAcceptedRoots(); err != nil {
+		t.Fatal(err)
+	}
+	var roots []*x509.Certificate
+	for _, pool := range AcceptedRootsMap {
+		if roots = pool.RawCertificates(); len(roots) >= 2 {
+			break
+		}
+	}
+	if len(roots) < 2 {
+		t.Fatal("no bundled Accepted Roots list has two roots")
+	}
+	pool := func(certs ...*x509.Certificate) *x509util.PEMCertPool {
+		p := x509util.NewPEMCertPool()
+		for _, cert := range certs {
+			p.AddCert(cert)
+		}
+		return p
+	}
+	family := ShardFamily{Operator: "Test", Name: "shard"}
+	for i := range 4 {
+		family.LogIDs = append(family.LogIDs, sha256.Sum256([]byte{'s', byte(i)}))
+	}
+
+	// Different Accepted Roots lists (e.g. in a different order) that accept the same roots are consistent. A shard with unknown Accepted Roots doesn't make the family inconsistent.
+	useShardRoots(t, family.LogIDs[:3], []*x509util.PEMCertPool{pool(roots[0], roots[1]), pool(roots[1], roots[0]), pool(roots[0], roots[1])})
+	report := CompareShardRoots(family)
+	if !report.Consistent || len(report.Missing) != 0 {
+		t.Errorf("CompareShardRoots reported Consistent = %v with %d missing roots, want true with none", report.Consistent, len(report.Missing))
+	}
+	if len(report.Shards) != 4 || report.Shards[3].RootsListHash != nil || report.Shards[0].Roots != 2 {
+		t.Errorf("CompareShardRoots reported shards %+v", report.Shards)
+	}
+
+	// A root missing from one shard makes the family inconsistent.
+	useShardRoots(t, family.LogIDs[:3], []*x509util.PEMCertPool{pool(roots[0], roots[1]), pool(roots[0]), pool(roots[1], roots[0])})
+	report = CompareShardRoots(family)
+	if report.Consistent || len(report.Missing) != 1 {
+		t.Fatalf("CompareShardRoots reported Consistent = %v with %d missing roots, want false with 1", report.Consistent, len(report.Missing))
+	}
+	if missing := report.Missing[0]; missing.Root.Fingerprint != sha256.Sum256(roots[1].Raw) || !slices.Equal(missing.MissingFrom, family.LogIDs[1:2]) {
+		t.Errorf("CompareShardRoots reported %s missing from %x, want %s missing from %x", missing.Root.Subject, missing.MissingFrom, roots[1].Subject, family.LogIDs[1])
+	}
+}